@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// auditDecision The decision details of an application
+type auditDecision struct {
+	Status             string              `json:"status"`
+	ReviewerID         string              `json:"reviewerId"`
+	DecisionCode       string              `json:"decisionCode"`
+	RejectionReason    string              `json:"rejectionReason"`
+	ApprovedAmount     int64               `json:"approvedAmount"`
+	InterestRateBps    int                 `json:"interestRateBps"`
+	Conditions         []ApprovalCondition `json:"conditions"`
+	ApprovalExpiryDate string              `json:"approvalExpiryDate"`
+}
+
+// auditHistoryEntry One recorded version of an application, redacted as the record is
+type auditHistoryEntry struct {
+	TxID      string          `json:"txId"`
+	Timestamp string          `json:"timestamp"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// auditBundle Everything recorded about an application
+type auditBundle struct {
+	Record        json.RawMessage     `json:"record"`
+	History       []auditHistoryEntry `json:"history"`
+	Notes         []Note              `json:"notes"`
+	Documents     []Document          `json:"documents"`
+	StatusHistory []StatusChange      `json:"statusHistory"`
+	Decision      auditDecision       `json:"decision"`
+}
+
+// GetApplicationAuditBundle Get the record, recorded history, notes, documents, status history and decision of
+// application args[0] in one document. Bank staff and auditors see everything the applicant has consented
+// to expose; other callers see the record and its history redacted and only applicant-visible notes.
+func GetApplicationAuditBundle(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationAuditBundle")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var loanAppID = args[0]
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := getLoanApplicationHistory(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	consented, err := piiConsentChecker(stub)
+	if err != nil {
+		return nil, err
+	}
+	privileged := checkRole(stub, "unredacted GetApplicationAuditBundle", roleAdmin, roleReviewer, roleAuditor) == nil
+	piiAllowed := privileged && consented(loanApplication)
+
+	// render Marshal a version of the application as this caller may see it
+	render := func(version LoanApplication) (json.RawMessage, error) {
+		if piiAllowed {
+			return json.Marshal(&version)
+		}
+		if !privileged {
+			version.Notes = visibleNotes(stub, version.Notes)
+		}
+		return redactLoanApplication(stub, version)
+	}
+
+	bundle := auditBundle{
+		History:       []auditHistoryEntry{},
+		Documents:     loanApplication.Documents,
+		StatusHistory: loanApplication.StatusHistory,
+		Decision: auditDecision{
+			Status:             loanApplication.Status,
+			ReviewerID:         loanApplication.ReviewerID,
+			DecisionCode:       loanApplication.DecisionCode,
+			RejectionReason:    loanApplication.RejectionReason,
+			ApprovedAmount:     loanApplication.ApprovedAmount,
+			InterestRateBps:    loanApplication.InterestRateBps,
+			Conditions:         loanApplication.Conditions,
+			ApprovalExpiryDate: loanApplication.ApprovalExpiryDate,
+		},
+	}
+	bundle.Notes = loanApplication.Notes
+	if !privileged {
+		bundle.Notes = visibleNotes(stub, loanApplication.Notes)
+	}
+	bundle.Record, err = render(loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		value, err := render(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		bundle.History = append(bundle.History, auditHistoryEntry{TxID: entry.TxID, Timestamp: entry.Timestamp, Value: value})
+	}
+
+	username, _ := GetCertAttribute(stub, "username")
+	logger.Info("Audit bundle of loan application " + loanAppID + " read by " + username)
+	return json.Marshal(&bundle)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// auditBundleOf Query the audit bundle of an application as the current caller
+func auditBundleOf(t *testing.T, stub *mockStub, loanAppID string) auditBundle {
+	t.Helper()
+	var bundle auditBundle
+	err := json.Unmarshal(stub.mustQuery(t, "GetApplicationAuditBundle", loanAppID), &bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundle
+}
+
+func TestGetApplicationAuditBundleSections(t *testing.T) {
+	stub := newMockStub()
+	loanApplication := testApplication("LA-1")
+	loanApplication.Documents = []Document{{Type: "payslip", Reference: "DOC-1"}}
+	createApplication(t, stub, loanApplication)
+	stub.mustInvoke(t, "AddNote", "LA-1", "income looks inflated")
+	stub.mustInvoke(t, "AddNote", "LA-1", "please upload a payslip", visibilityApplicant)
+	stub.mustInvoke(t, "UpdateLoanApplication", "LA-1", statusUnderReview)
+	stub.mustInvoke(t, "RejectLoanApplication", "LA-1", "DTI_TOO_HIGH", "debt too high")
+
+	bundle := auditBundleOf(t, stub.as("auditor", roleAuditor), "LA-1")
+	var record LoanApplication
+	if err := json.Unmarshal(bundle.Record, &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.ID != "LA-1" || record.PersonalInfo.Lastname != "Doe-LA-1" {
+		t.Fatalf("expected the full record, got %+v", record)
+	}
+	if len(bundle.History) != 5 {
+		t.Fatalf("expected a history entry per transaction, got %d", len(bundle.History))
+	}
+	if len(bundle.Notes) != 2 {
+		t.Fatalf("expected both notes, got %+v", bundle.Notes)
+	}
+	if len(bundle.Documents) != 1 || bundle.Documents[0].Reference != "DOC-1" {
+		t.Fatalf("expected the attached document, got %+v", bundle.Documents)
+	}
+	if len(bundle.StatusHistory) == 0 || bundle.StatusHistory[len(bundle.StatusHistory)-1].Status != statusRejected {
+		t.Fatalf("expected the status history to end in rejection, got %+v", bundle.StatusHistory)
+	}
+	if bundle.Decision.Status != statusRejected || bundle.Decision.DecisionCode != "DTI_TOO_HIGH" || bundle.Decision.RejectionReason != "debt too high" {
+		t.Fatalf("expected the rejection decision, got %+v", bundle.Decision)
+	}
+}
+
+func TestGetApplicationAuditBundleRedactsForApplicants(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+	stub.mustInvoke(t, "AddNote", "LA-1", "income looks inflated")
+	stub.mustInvoke(t, "AddNote", "LA-1", "please upload a payslip", visibilityApplicant)
+
+	bundle := auditBundleOf(t, stub.as("applicant", roleApplicant), "LA-1")
+	if len(bundle.Notes) != 1 || bundle.Notes[0].Visibility != visibilityApplicant {
+		t.Fatalf("expected only the applicant-visible note, got %+v", bundle.Notes)
+	}
+	var record LoanApplication
+	if err := json.Unmarshal(bundle.Record, &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.PersonalInfo.Lastname != "D***" || len(record.Notes) != 1 {
+		t.Fatalf("expected a masked record with applicant-visible notes, got %+v", record)
+	}
+	for _, entry := range bundle.History {
+		var version LoanApplication
+		if err := json.Unmarshal(entry.Value, &version); err != nil {
+			t.Fatal(err)
+		}
+		if version.PersonalInfo.Lastname != "D***" {
+			t.Fatalf("expected history entry %s masked, got %s", entry.TxID, version.PersonalInfo.Lastname)
+		}
+	}
+}
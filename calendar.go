@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// businessCalendarKey Ledger key of the admin-managed business calendar
+const businessCalendarKey = "businessCalendar"
+
+// BusinessCalendar Days excluded from SLA and turnaround hours. With nothing configured every
+// day counts, so durations are plain elapsed time.
+type BusinessCalendar struct {
+	// Weekend Day names such as "Saturday" that are never business days
+	Weekend []string `json:"weekend"`
+	// Holidays Dates in YYYY-MM-DD form that are not business days
+	Holidays []string `json:"holidays"`
+}
+
+// weekdays Day names accepted in a calendar weekend
+var weekdays = map[string]time.Weekday{
+	"Sunday":    time.Sunday,
+	"Monday":    time.Monday,
+	"Tuesday":   time.Tuesday,
+	"Wednesday": time.Wednesday,
+	"Thursday":  time.Thursday,
+	"Friday":    time.Friday,
+	"Saturday":  time.Saturday,
+}
+
+// getBusinessCalendar Load the business calendar, empty if none has been set
+func getBusinessCalendar(stub shim.ChaincodeStubInterface) (BusinessCalendar, error) {
+	var calendar BusinessCalendar
+	_, err := getConfig(stub, businessCalendarKey, &calendar)
+	return calendar, err
+}
+
+// isBusinessDay Report whether a UTC day counts towards business hours
+func (c BusinessCalendar) isBusinessDay(day time.Time) bool {
+	for _, name := range c.Weekend {
+		if weekdays[name] == day.Weekday() {
+			return false
+		}
+	}
+	date := day.Format(dateLayout)
+	for _, holiday := range c.Holidays {
+		if holiday == date {
+			return false
+		}
+	}
+	return true
+}
+
+// businessHoursBetween Hours from one time to another that fall on business days, measured in UTC.
+// Negative when to is before from.
+func (c BusinessCalendar) businessHoursBetween(from, to time.Time) float64 {
+	if to.Before(from) {
+		return -c.businessHoursBetween(to, from)
+	}
+	from, to = from.UTC(), to.UTC()
+
+	var total time.Duration
+	for dayStart := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC); dayStart.Before(to); dayStart = dayStart.AddDate(0, 0, 1) {
+		if !c.isBusinessDay(dayStart) {
+			continue
+		}
+		start, end := dayStart, dayStart.AddDate(0, 0, 1)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		total += end.Sub(start)
+	}
+	return total.Hours()
+}
+
+// SetBusinessCalendar Replace the business calendar with the JSON in args[0]
+func SetBusinessCalendar(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetBusinessCalendar")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected business calendar argument")
+	}
+
+	var calendar BusinessCalendar
+	err := json.Unmarshal([]byte(args[0]), &calendar)
+	if err != nil {
+		logger.Error("Could not unmarshal business calendar", err)
+		return nil, errors.New("Invalid business calendar: " + err.Error())
+	}
+	for _, name := range calendar.Weekend {
+		if _, ok := weekdays[name]; !ok {
+			return nil, errors.New("Unknown weekend day '" + name + "'")
+		}
+	}
+	for _, holiday := range calendar.Holidays {
+		if _, err := time.Parse(dateLayout, holiday); err != nil {
+			return nil, errors.New("Holiday '" + holiday + "' must be in " + dateLayout + " format")
+		}
+	}
+
+	err = putConfig(stub, businessCalendarKey, &calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully saved business calendar")
+	return nil, nil
+}
+
+// GetBusinessCalendar Get the current business calendar
+func GetBusinessCalendar(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetBusinessCalendar")
+
+	calendar, err := getBusinessCalendar(stub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&calendar)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBusinessHoursBetweenSkipsWeekendsAndHolidays(t *testing.T) {
+	calendar := BusinessCalendar{Weekend: []string{"Saturday", "Sunday"}, Holidays: []string{"2024-03-18"}}
+	friday := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		to       time.Time
+		expected float64
+	}{
+		{friday.Add(6 * time.Hour), 6},
+		{friday.AddDate(0, 0, 1), 12},
+		{friday.AddDate(0, 0, 3), 12},
+		{friday.AddDate(0, 0, 4), 24},
+		{friday.AddDate(0, 0, 7), 96},
+	}
+	for _, c := range cases {
+		if hours := calendar.businessHoursBetween(friday, c.to); hours != c.expected {
+			t.Errorf("expected %v business hours to %s, got %v", c.expected, c.to.Format(time.RFC3339), hours)
+		}
+	}
+	if hours := calendar.businessHoursBetween(friday.AddDate(0, 0, 4), friday); hours != -24 {
+		t.Errorf("expected a reversed range to be negative, got %v", hours)
+	}
+	if hours := (BusinessCalendar{}).businessHoursBetween(friday, friday.AddDate(0, 0, 4)); hours != 96 {
+		t.Errorf("expected plain elapsed hours with no calendar, got %v", hours)
+	}
+}
+
+func TestStaleAssignmentsUseBusinessCalendar(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"reviewSlaHours":24}`)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+	// 71 elapsed hours from Friday noon, but only 23 on business days
+	stub.now = stub.now.Add(71 * time.Hour)
+
+	stale := func() map[string]json.RawMessage {
+		var stale map[string]json.RawMessage
+		err := json.Unmarshal(stub.mustQuery(t, "GetStaleAssignments"), &stale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stale
+	}
+	if len(stale()) != 1 {
+		t.Fatal("expected the review to breach the SLA in elapsed hours")
+	}
+	stub.mustInvoke(t, "SetBusinessCalendar", `{"weekend":["Saturday","Sunday"]}`)
+	if len(stale()) != 0 {
+		t.Fatal("expected the weekend not to count towards the SLA")
+	}
+
+	for _, calendar := range []string{`{"weekend":["Caturday"]}`, `{"holidays":["18/03/2024"]}`} {
+		if _, err := stub.invoke("SetBusinessCalendar", calendar); err == nil {
+			t.Errorf("expected calendar %s to be rejected", calendar)
+		}
+	}
+}
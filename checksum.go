@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// canonicalJSON Re-encode JSON with object keys sorted, no insignificant whitespace, numbers
+// kept exactly as written and no HTML escaping, so equal documents always encode identically
+func canonicalJSON(input []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	err = encoder.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
+
+// loanApplicationChecksum Checksum returned for an application
+type loanApplicationChecksum struct {
+	ID        string `json:"id"`
+	Algorithm string `json:"algorithm"`
+	Checksum  string `json:"checksum"`
+}
+
+// GetLoanApplicationChecksum Get the hex SHA-256 of the canonical JSON of application args[0]
+func GetLoanApplicationChecksum(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationChecksum")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var loanAppID = args[0]
+
+	loanAppBytes, err := stub.GetState(loanKey(loanAppID))
+	if err != nil {
+		logger.Error("Could not fetch loan application with id "+loanAppID+" from ledger", err)
+		return nil, err
+	}
+	if loanAppBytes == nil {
+		return nil, errors.New("Loan application " + loanAppID + " does not exist")
+	}
+
+	canonical, err := canonicalJSON(loanAppBytes)
+	if err != nil {
+		logger.Error("Could not canonicalise loan application "+loanAppID, err)
+		return nil, err
+	}
+	sum := sha256.Sum256(canonical)
+
+	return json.Marshal(loanApplicationChecksum{
+		ID:        loanAppID,
+		Algorithm: "sha256",
+		Checksum:  hex.EncodeToString(sum[:]),
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// checksumOf Checksum returned for an application
+func checksumOf(t *testing.T, stub *mockStub, loanAppID string) string {
+	t.Helper()
+	var checksum loanApplicationChecksum
+	err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationChecksum", loanAppID), &checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checksum.Algorithm != "sha256" || checksum.ID != loanAppID {
+		t.Fatalf("unexpected checksum %+v", checksum)
+	}
+	return checksum.Checksum
+}
+
+func TestGetLoanApplicationChecksumStableAcrossMarshaling(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+	stored := stub.state[loanKey("LA-1")]
+	checksum := checksumOf(t, stub, "LA-1")
+
+	expected := sha256.Sum256(stored)
+	if checksum != hex.EncodeToString(expected[:]) {
+		t.Fatal("expected the checksum of the stored canonical JSON")
+	}
+
+	// The same record written with keys in reverse order and extra whitespace has the same checksum
+	var object map[string]json.RawMessage
+	err := json.Unmarshal(stored, &object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	var buffer bytes.Buffer
+	buffer.WriteString("{\n")
+	for i, key := range keys {
+		if i > 0 {
+			buffer.WriteString(",\n")
+		}
+		buffer.WriteString("  " + strconv.Quote(key) + ": ")
+		buffer.Write(object[key])
+	}
+	buffer.WriteString("\n}")
+	stub.state[loanKey("LA-1")] = buffer.Bytes()
+	if got := checksumOf(t, stub, "LA-1"); got != checksum {
+		t.Fatalf("expected a stable checksum, got %s and %s", checksum, got)
+	}
+
+	loanApplication := storedApplication(t, stub, "LA-1")
+	loanApplication.RequestedAmount++
+	seedApplication(t, stub, loanApplication)
+	if got := checksumOf(t, stub, "LA-1"); got == checksum {
+		t.Fatal("expected a changed record to change the checksum")
+	}
+	if _, err := stub.query("GetLoanApplicationChecksum", "LA-9"); err == nil {
+		t.Fatal("expected a missing application to be rejected")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// CloneLoanApplication Copy the applicant, property and financial details of application args[0] into a
+// new Submitted application with ID args[1], generating an LN- ID when args[1] is empty or absent.
+// Applicants may only clone applications made out to them as buyer.
+func CloneLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering CloneLoanApplication")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var sourceID = args[0]
+	var loanAppID string
+	if len(args) > 1 {
+		loanAppID = args[1]
+	}
+
+	source, err := getLoanApplication(stub, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	username, _ := GetCertAttribute(stub, "username")
+	role, _ := GetCertAttribute(stub, "role")
+	if role != roleAdmin && !ownsApplication(stub, source) {
+		return nil, &PermissionError{Username: username, Role: role, Function: "CloneLoanApplication of " + sourceID}
+	}
+	if source.PIIPurged {
+		return nil, errors.New("Loan application " + sourceID + " has had its personal information purged and cannot be cloned")
+	}
+
+	staged := newStagedStub(stub)
+	generatedID := loanAppID == ""
+	if generatedID {
+		loanAppID, err = nextLoanApplicationID(staged)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		existing, err := stub.GetState(loanKey(loanAppID))
+		if err != nil {
+			logger.Error("Could not fetch loan application "+loanAppID+" from ledger", err)
+			return nil, err
+		}
+		if existing != nil {
+			return nil, errors.New("Loan application " + loanAppID + " already exists")
+		}
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only what the applicant supplied is copied, so no decision, review or workflow state carries over
+	loanApplication := LoanApplication{
+		ID:                      loanAppID,
+		PropertyID:              source.PropertyID,
+		LandID:                  source.LandID,
+		PermitID:                source.PermitID,
+		BuyerID:                 source.BuyerID,
+		SalesContractID:         source.SalesContractID,
+		ProductType:             source.ProductType,
+		PersonalInfo:            source.PersonalInfo,
+		FinancialInfo:           source.FinancialInfo,
+		RequestedAmount:         source.RequestedAmount,
+		TermMonths:              source.TermMonths,
+		Currency:                source.Currency,
+		FairMarketValue:         source.FairMarketValue,
+		FairMarketValueCurrency: source.FairMarketValueCurrency,
+		AppraisalDate:           source.AppraisalDate,
+		CreditTier:              source.CreditTier,
+		Documents:               append([]Document(nil), source.Documents...),
+		CreatedBy:               username,
+		CreatedByRole:           role,
+		CreatedDate:             now.Format(time.RFC3339),
+	}
+
+	violations, err := validateLoanApplication(stub, loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		logger.Error("Cloned loan application failed validation")
+		return nil, errors.New("Invalid loan application: " + strings.Join(violations, "; "))
+	}
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	err = checkPropertyCapacity(stub, loanApplication.PropertyID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setStatus(stub, &loanApplication, statusSubmitted, "cloned from "+sourceID)
+	if err != nil {
+		return nil, err
+	}
+	err = saveLoanApplication(staged, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	err = addLoanIndexes(staged, loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	err = sendEvent(staged, "loanApplicationCreation", loanAppID+" cloned from "+sourceID, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = staged.flush()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully cloned loan application")
+	return json.Marshal(createResponse{ID: loanAppID})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCloneLoanApplicationIsFreshDraft(t *testing.T) {
+	stub := newMockStub()
+	source := testApplication("LA-1")
+	source.BuyerID = "app1"
+	createApplication(t, stub, source)
+	source = storedApplication(t, stub, "LA-1")
+	source.Status = statusRejected
+	source.ReviewerID = "rev1"
+	source.ApprovedAmount = 250000
+	source.InterestRateBps = 500
+	source.DecisionCode = "DTI_TOO_HIGH"
+	source.RejectionReason = "over the limit"
+	source.Tags = []string{"fraud-watch"}
+	source.Notes = []Note{{Text: "called applicant", Visibility: visibilityInternal}}
+	seedApplication(t, stub, source)
+
+	var response createResponse
+	err := json.Unmarshal(stub.as("app1", roleApplicant).mustInvoke(t, "CloneLoanApplication", "LA-1", "LA-2"), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.ID != "LA-2" {
+		t.Fatalf("expected the clone ID in the response, got %+v", response)
+	}
+
+	clone := storedApplication(t, stub, "LA-2")
+	if clone.Status != statusSubmitted || clone.ReviewerID != "" || clone.ApprovedAmount != 0 || clone.InterestRateBps != 0 ||
+		clone.DecisionCode != "" || clone.RejectionReason != "" || len(clone.Tags) != 0 || len(clone.Notes) != 0 {
+		t.Fatalf("expected a fresh Submitted draft, got %+v", clone)
+	}
+	if clone.PersonalInfo != source.PersonalInfo || clone.FinancialInfo != source.FinancialInfo || clone.RequestedAmount != source.RequestedAmount || clone.PropertyID != source.PropertyID {
+		t.Fatal("expected the applicant's details to be copied")
+	}
+	if clone.CreatedBy != "app1" || len(clone.StatusHistory) != 1 || clone.StatusHistory[0].Note != "cloned from LA-1" {
+		t.Fatalf("expected a single cloned status entry by app1, got %+v", clone.StatusHistory)
+	}
+	if storedApplication(t, stub, "LA-1").Status != statusRejected {
+		t.Fatal("expected the source to be untouched")
+	}
+	assertIDs(t, recordIDs(t, stub.as("admin", roleAdmin).mustQuery(t, "QueryByStatuses", statusSubmitted)), "LA-2")
+}
+
+func TestCloneLoanApplicationRestrictedToOwnerOrAdmin(t *testing.T) {
+	stub := newMockStub()
+	source := testApplication("LA-1")
+	source.BuyerID = "app1"
+	createApplication(t, stub, source)
+
+	_, err := stub.as("app2", roleApplicant).invoke("CloneLoanApplication", "LA-1", "LA-2")
+	if _, ok := err.(*PermissionError); !ok {
+		t.Fatalf("expected another applicant to be denied, got %v", err)
+	}
+	if _, err := stub.as("rev1", roleReviewer).invoke("CloneLoanApplication", "LA-1", "LA-2"); err == nil {
+		t.Fatal("expected reviewers to be denied")
+	}
+	stub.as("admin", roleAdmin).mustInvoke(t, "CloneLoanApplication", "LA-1", "LA-2")
+	if _, err := stub.invoke("CloneLoanApplication", "LA-1", "LA-2"); err == nil {
+		t.Fatal("expected cloning onto an existing ID to be rejected")
+	}
+}
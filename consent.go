@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Consent An applicant's recorded consent of one type. Applications keep consents sorted by type
+// so the stored record is deterministic.
+type Consent struct {
+	Type         string `json:"type"`
+	Granted      bool   `json:"granted"`
+	RecordedBy   string `json:"recordedBy"`
+	RecordedDate string `json:"recordedDate"`
+	ExpiryDate   string `json:"expiryDate,omitempty"`
+}
+
+// isCurrent Report whether a consent is granted and has not lapsed
+func (c Consent) isCurrent(now time.Time) bool {
+	if !c.Granted {
+		return false
+	}
+	if c.ExpiryDate == "" {
+		return true
+	}
+	expiry, err := time.Parse(time.RFC3339, c.ExpiryDate)
+	return err == nil && now.Before(expiry)
+}
+
+// missingConsents List the required consent types an application does not currently hold
+func missingConsents(loanApplication LoanApplication, params LoanParameters, now time.Time) []string {
+	current := map[string]bool{}
+	for _, consent := range loanApplication.Consents {
+		if consent.isCurrent(now) {
+			current[consent.Type] = true
+		}
+	}
+
+	var missing []string
+	for _, consentType := range params.RequiredConsentTypes {
+		if !current[consentType] {
+			missing = append(missing, consentType)
+		}
+	}
+	return missing
+}
+
+// piiConsentChecker Build a check of whether an application's personal information may be exposed,
+// loading the parameters and transaction time once for use across many records
+func piiConsentChecker(stub shim.ChaincodeStubInterface) (func(LoanApplication) bool, error) {
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	return func(loanApplication LoanApplication) bool {
+		return len(missingConsents(loanApplication, params, now)) == 0
+	}, nil
+}
+
+// RecordConsent Record consent of type args[1] as granted or withdrawn by args[2] ("true" or "false") on
+// application args[0], optionally lapsing at RFC3339 time args[3]. Applicants may only record consent on
+// applications made out to them as buyer.
+func RecordConsent(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering RecordConsent")
+
+	if len(args) < 3 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID, consent type and consent value")
+	}
+
+	var loanAppID = args[0]
+	var consentType = args[1]
+	var expiryDate string
+	if len(args) > 3 {
+		expiryDate = args[3]
+	}
+
+	if consentType == "" {
+		return nil, errors.New("Consent type cannot be empty")
+	}
+	granted, err := strconv.ParseBool(args[2])
+	if err != nil {
+		return nil, errors.New("Consent value must be true or false")
+	}
+	if expiryDate != "" {
+		if _, err := time.Parse(time.RFC3339, expiryDate); err != nil {
+			return nil, errors.New("Consent expiry must be an RFC3339 timestamp")
+		}
+	}
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	username, _ := GetCertAttribute(stub, "username")
+	role, _ := GetCertAttribute(stub, "role")
+	if role == roleApplicant && !ownsApplication(stub, loanApplication) {
+		return nil, &PermissionError{Username: username, Role: role, Function: "RecordConsent on " + loanAppID}
+	}
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	consent := Consent{
+		Type:         consentType,
+		Granted:      granted,
+		RecordedBy:   username,
+		RecordedDate: now.Format(time.RFC3339),
+		ExpiryDate:   expiryDate,
+	}
+	// A new record of a type replaces the previous one
+	consents := []Consent{consent}
+	for _, existing := range loanApplication.Consents {
+		if existing.Type != consentType {
+			consents = append(consents, existing)
+		}
+	}
+	sort.Slice(consents, func(i, j int) bool {
+		return consents[i].Type < consents[j].Type
+	})
+	loanApplication.Consents = consents
+
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "consentRecorded", loanAppID+" consent "+consentType+" set to "+args[2], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully recorded consent")
+	return nil, nil
+}
+
+// expiredConsents An application and its required consents that have lapsed
+type expiredConsents struct {
+	ID      string   `json:"id"`
+	Expired []string `json:"expired"`
+}
+
+// GetApplicationsWithExpiredConsent Get applications holding a required consent whose expiry has passed
+func GetApplicationsWithExpiredConsent(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationsWithExpiredConsent")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	required := map[string]bool{}
+	for _, consentType := range params.RequiredConsentTypes {
+		required[consentType] = true
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []expiredConsents{}
+	for _, loanApplication := range loanApplications {
+		var expired []string
+		for _, consent := range loanApplication.Consents {
+			if !required[consent.Type] || !consent.Granted || consent.ExpiryDate == "" {
+				continue
+			}
+			if !consent.isCurrent(now) {
+				expired = append(expired, consent.Type)
+			}
+		}
+		if len(expired) > 0 {
+			results = append(results, expiredConsents{ID: loanApplication.ID, Expired: expired})
+		}
+	}
+
+	return json.Marshal(results)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMissingConsentBlocksPIIRead(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"requiredConsentTypes":["pii"]}`)
+	createApplication(t, stub, testApplication("LA-1"))
+
+	if _, err := stub.as("auditor", roleAuditor).query("GetLoanApplicationRaw", "LA-1"); err == nil {
+		t.Fatal("expected the raw read to be blocked without consent")
+	}
+	var masked LoanApplication
+	stub.as("admin", roleAdmin)
+	if err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplication", "LA-1"), &masked); err != nil {
+		t.Fatal(err)
+	}
+	if masked.PersonalInfo.Lastname != "D***" {
+		t.Fatalf("expected staff to see masked personal information without consent, got %s", masked.PersonalInfo.Lastname)
+	}
+
+	stub.mustInvoke(t, "RecordConsent", "LA-1", "pii", "true")
+	var raw LoanApplication
+	if err := json.Unmarshal(stub.as("auditor", roleAuditor).mustQuery(t, "GetLoanApplicationRaw", "LA-1"), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw.PersonalInfo.Lastname != "Doe-LA-1" {
+		t.Fatalf("expected personal information once consent is recorded, got %s", raw.PersonalInfo.Lastname)
+	}
+
+	stub.as("admin", roleAdmin).mustInvoke(t, "RecordConsent", "LA-1", "pii", "false")
+	if _, err := stub.as("auditor", roleAuditor).query("GetLoanApplicationRaw", "LA-1"); err == nil {
+		t.Fatal("expected the raw read to be blocked once consent is withdrawn")
+	}
+}
+
+func TestRecordConsentStoresSortedRecords(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+	expiry := stub.now.AddDate(1, 0, 0).Format(time.RFC3339)
+
+	stub.as("officer", roleReviewer)
+	stub.mustInvoke(t, "RecordConsent", "LA-1", "pii", "true", expiry)
+	stub.mustInvoke(t, "RecordConsent", "LA-1", "marketing", "false")
+	stub.mustInvoke(t, "RecordConsent", "LA-1", "pii", "true")
+
+	consents := storedApplication(t, stub, "LA-1").Consents
+	if len(consents) != 2 || consents[0].Type != "marketing" || consents[1].Type != "pii" {
+		t.Fatalf("expected one consent per type sorted by type, got %+v", consents)
+	}
+	pii := consents[1]
+	if !pii.Granted || pii.RecordedBy != "officer" || pii.RecordedDate != stub.now.Format(time.RFC3339) || pii.ExpiryDate != "" {
+		t.Fatalf("expected the latest pii consent to replace the earlier one, got %+v", pii)
+	}
+
+	for _, args := range [][]string{
+		{"LA-1", "pii", "yes"},
+		{"LA-1", "", "true"},
+		{"LA-1", "pii", "true", "next year"},
+	} {
+		if _, err := stub.invoke("RecordConsent", args...); err == nil {
+			t.Errorf("expected RecordConsent%v to be rejected", args)
+		}
+	}
+}
+
+func TestRecordConsentApplicantOwnership(t *testing.T) {
+	stub := newMockStub()
+	loanApplication := testApplication("LA-1")
+	loanApplication.BuyerID = "applicant1"
+	createApplication(t, stub, loanApplication)
+
+	stub.as("applicant1", roleApplicant).mustInvoke(t, "RecordConsent", "LA-1", "pii", "true")
+	_, err := stub.as("applicant2", roleApplicant).invoke("RecordConsent", "LA-1", "pii", "false")
+	if _, ok := err.(*PermissionError); !ok {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if !storedApplication(t, stub, "LA-1").Consents[0].Granted {
+		t.Fatal("expected another applicant's withdrawal to be discarded")
+	}
+}
+
+func TestGetApplicationsWithExpiredConsent(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"requiredConsentTypes":["credit","pii"]}`)
+	past := stub.now.Add(-time.Hour).Format(time.RFC3339)
+	future := stub.now.Add(time.Hour).Format(time.RFC3339)
+	seeds := map[string][]Consent{
+		"LA-1": {{Type: "credit", Granted: true, ExpiryDate: past}, {Type: "pii", Granted: true, ExpiryDate: past}},
+		"LA-2": {{Type: "credit", Granted: true, ExpiryDate: future}, {Type: "pii", Granted: true}},
+		"LA-3": {{Type: "marketing", Granted: true, ExpiryDate: past}, {Type: "pii", Granted: true, ExpiryDate: future}},
+		"LA-4": {{Type: "pii", Granted: false, ExpiryDate: past}},
+		"LA-5": {{Type: "pii", Granted: true, ExpiryDate: stub.now.Format(time.RFC3339)}},
+	}
+	for id, consents := range seeds {
+		loanApplication := testApplication(id)
+		loanApplication.Consents = consents
+		seedApplication(t, stub, loanApplication)
+	}
+
+	var results []expiredConsents
+	err := json.Unmarshal(stub.as("auditor", roleAuditor).mustQuery(t, "GetApplicationsWithExpiredConsent"), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected LA-1 and LA-5, got %+v", results)
+	}
+	if results[0].ID != "LA-1" || len(results[0].Expired) != 2 {
+		t.Fatalf("expected both LA-1 consents expired, got %+v", results[0])
+	}
+	if results[1].ID != "LA-5" || len(results[1].Expired) != 1 || results[1].Expired[0] != "pii" {
+		t.Fatalf("expected consent lapsing at tx time to count as expired, got %+v", results[1])
+	}
+
+	stub.now = stub.now.Add(2 * time.Hour)
+	if err := json.Unmarshal(stub.mustQuery(t, "GetApplicationsWithExpiredConsent"), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected LA-2 and LA-3 to lapse as well, got %+v", results)
+	}
+	if _, err := stub.as("rev1", roleReviewer).query("GetApplicationsWithExpiredConsent"); err == nil {
+		t.Fatal("expected reviewers to be denied")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// remainingDisbursement Approved amount not yet paid out
+func remainingDisbursement(loanApplication LoanApplication) int64 {
+	return loanApplication.ApprovedAmount - loanApplication.DisbursementInfo.DisbursedAmount
+}
+
+// DisburseLoanApplication Pay out tranche args[1] of approved application args[0], or the remainder when empty,
+// using replay nonce args[2]
+func DisburseLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering DisburseLoanApplication")
+
+	if len(args) < 3 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID, amount and nonce")
+	}
+
+	var loanAppID = args[0]
+
+	err := consumeNonce(stub, loanAppID, nonceOpDisburse, args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	if loanApplication.Status != statusApproved {
+		return nil, errors.New("Loan application " + loanAppID + " is " + loanApplication.Status + " and cannot be disbursed")
+	}
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	// An undrawn approval lapses at its expiry date whether or not ExpireApprovals has run yet
+	if loanApplication.ApprovalExpiryDate != "" && loanApplication.DisbursementInfo.DisbursedAmount == 0 {
+		expiry, err := time.Parse(time.RFC3339, loanApplication.ApprovalExpiryDate)
+		if err == nil && now.After(expiry) {
+			return nil, errors.New("Approval of loan application " + loanAppID + " expired on " + loanApplication.ApprovalExpiryDate + " and cannot be disbursed")
+		}
+	}
+
+	remaining := remainingDisbursement(loanApplication)
+	amount := remaining
+	if len(args) > 1 && args[1] != "" {
+		amount, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil || amount <= 0 {
+			return nil, errors.New("Disbursement amount must be a positive integer")
+		}
+	}
+	if amount <= 0 || amount > remaining {
+		return nil, errors.New("Disbursement amount exceeds the " + strconv.FormatInt(remaining, 10) + " remaining on loan application " + loanAppID)
+	}
+
+	info := &loanApplication.DisbursementInfo
+	info.DisbursedAmount, err = addMoney(info.DisbursedAmount, amount)
+	if err != nil {
+		return nil, err
+	}
+	info.Tranches = append(info.Tranches, Tranche{Amount: amount, Date: now.Format(time.RFC3339), TxID: stub.GetTxID()})
+
+	if remainingDisbursement(loanApplication) == 0 {
+		err = setStatus(stub, &loanApplication, statusDisbursed, "fully disbursed")
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "loanApplicationDisbursement", loanAppID+" disbursed "+strconv.FormatInt(amount, 10), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully disbursed loan application tranche")
+	return nil, nil
+}
+
+// GetLoanApplicationsPendingDisbursement Get approved applications with funds still to be paid
+func GetLoanApplicationsPendingDisbursement(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsPendingDisbursement")
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []LoanApplication{}
+	var remainders []map[string]interface{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.Status != statusApproved {
+			continue
+		}
+		if remaining := remainingDisbursement(loanApplication); remaining > 0 {
+			pending = append(pending, loanApplication)
+			remainders = append(remainders, map[string]interface{}{"remainingAmount": remaining})
+		}
+	}
+
+	views, err := viewLoanApplications(stub, pending, remainders)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(views)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// seedApproved Seed an application approved for the given amount
+func seedApproved(t *testing.T, stub *mockStub, loanAppID string, approvedAmount int64) {
+	t.Helper()
+	loanApplication := testApplication(loanAppID)
+	loanApplication.Status = statusApproved
+	loanApplication.ReviewerID = "rev1"
+	loanApplication.ApprovedAmount = approvedAmount
+	loanApplication.InterestRateBps = 500
+	seedApplication(t, stub, loanApplication)
+}
+
+func TestGetLoanApplicationsPendingDisbursement(t *testing.T) {
+	stub := newMockStub()
+	seedApproved(t, stub, "LA-1", 300000)
+	seedApproved(t, stub, "LA-2", 300000)
+	seedApproved(t, stub, "LA-3", 200000)
+	stub.mustInvoke(t, "DisburseLoanApplication", "LA-2", "100000", "n1")
+	stub.mustInvoke(t, "DisburseLoanApplication", "LA-3", "", "n1")
+
+	if status := storedApplication(t, stub, "LA-3").Status; status != statusDisbursed {
+		t.Fatalf("expected the fully paid LA-3 to be Disbursed, got %s", status)
+	}
+	records := decodeRecords(t, stub.mustQuery(t, "GetLoanApplicationsPendingDisbursement"))
+	if len(records) != 2 || records[0]["id"] != "LA-1" || records[1]["id"] != "LA-2" {
+		t.Fatalf("expected LA-1 and LA-2 pending, got %v", records)
+	}
+	if records[0]["remainingAmount"] != 300000.0 || records[1]["remainingAmount"] != 200000.0 {
+		t.Fatalf("expected remaining 300000 and 200000, got %v and %v", records[0]["remainingAmount"], records[1]["remainingAmount"])
+	}
+}
+
+func TestDisburseLoanApplicationRejectsOverpayment(t *testing.T) {
+	stub := newMockStub()
+	seedApproved(t, stub, "LA-1", 300000)
+
+	if _, err := stub.invoke("DisburseLoanApplication", "LA-1", "300001", "n1"); err == nil {
+		t.Fatal("expected a tranche above the remaining amount to be rejected")
+	}
+	if storedApplication(t, stub, "LA-1").DisbursementInfo.DisbursedAmount != 0 {
+		t.Fatal("expected nothing to be disbursed")
+	}
+}
+
+func TestDisburseLoanApplicationRejectsExpiredApproval(t *testing.T) {
+	stub := newMockStub()
+	for _, id := range []string{"LA-1", "LA-2"} {
+		seedApproved(t, stub, id, 300000)
+		loanApplication := storedApplication(t, stub, id)
+		loanApplication.ApprovalExpiryDate = stub.now.AddDate(0, 0, 30).Format(time.RFC3339)
+		seedApplication(t, stub, loanApplication)
+	}
+
+	stub.mustInvoke(t, "DisburseLoanApplication", "LA-1", "100000", "n1")
+	stub.now = stub.now.AddDate(0, 0, 31)
+	if _, err := stub.invoke("DisburseLoanApplication", "LA-2", "100000", "n2"); err == nil {
+		t.Fatal("expected drawing an undrawn approval past its expiry to be rejected")
+	}
+	if storedApplication(t, stub, "LA-2").DisbursementInfo.DisbursedAmount != 0 {
+		t.Fatal("expected nothing to be disbursed")
+	}
+	// Once drawn the approval no longer lapses, as ExpireApprovals leaves it alone
+	stub.mustInvoke(t, "DisburseLoanApplication", "LA-1", "", "n3")
+	if status := storedApplication(t, stub, "LA-1").Status; status != statusDisbursed {
+		t.Fatalf("expected LA-1 to finish disbursing, got %s", status)
+	}
+}
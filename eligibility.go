@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// debtToIncome Monthly loan payments over monthly salary, zero when there is no salary
+func debtToIncome(loanApplication LoanApplication) float64 {
+	info := loanApplication.FinancialInfo
+	if info.MonthlySalary <= 0 {
+		return 0
+	}
+	return float64(info.MonthlyLoanPayment) / float64(info.MonthlySalary)
+}
+
+// applicantAge Age in whole years of the applicant at the given time
+func applicantAge(loanApplication LoanApplication, at time.Time) (int, error) {
+	dob, err := time.Parse(dateLayout, loanApplication.PersonalInfo.DOB)
+	if err != nil {
+		return 0, err
+	}
+	age := at.Year() - dob.Year()
+	if at.Month() < dob.Month() || (at.Month() == dob.Month() && at.Day() < dob.Day()) {
+		age--
+	}
+	return age, nil
+}
+
+// eligibilityReasons Reasons an application would fail approval under the given parameters,
+// empty when it is eligible. Limits left at zero are not enforced.
+func eligibilityReasons(loanApplication LoanApplication, params LoanParameters, now time.Time) []string {
+	reasons := []string{}
+
+	if params.MaxDebtToIncome > 0 {
+		if loanApplication.FinancialInfo.MonthlySalary <= 0 {
+			reasons = append(reasons, "no monthly salary to assess debt-to-income")
+		} else if dti := debtToIncome(loanApplication); dti > params.MaxDebtToIncome {
+			reasons = append(reasons, fmt.Sprintf("debt-to-income %.2f exceeds maximum %.2f", dti, params.MaxDebtToIncome))
+		}
+	}
+
+	if params.MaxLoanToValue > 0 {
+		if loanApplication.FairMarketValue <= 0 {
+			reasons = append(reasons, "no fair market value to assess loan-to-value")
+		} else if ltv, err := loanToValue(loanApplication, params); err != nil {
+			reasons = append(reasons, "cannot assess loan-to-value: "+err.Error())
+		} else if ltv > params.MaxLoanToValue {
+			reasons = append(reasons, fmt.Sprintf("loan-to-value %.2f exceeds maximum %.2f", ltv, params.MaxLoanToValue))
+		}
+	}
+
+	if params.MinApplicantAge > 0 || params.MaxApplicantAge > 0 {
+		age, err := applicantAge(loanApplication, now)
+		switch {
+		case err != nil:
+			reasons = append(reasons, "applicant date of birth is invalid")
+		case params.MinApplicantAge > 0 && age < params.MinApplicantAge:
+			reasons = append(reasons, fmt.Sprintf("applicant age %d is below minimum %d", age, params.MinApplicantAge))
+		case params.MaxApplicantAge > 0 && age > params.MaxApplicantAge:
+			reasons = append(reasons, fmt.Sprintf("applicant age %d is above maximum %d", age, params.MaxApplicantAge))
+		}
+	}
+
+	for _, documentType := range missingDocumentTypes(loanApplication, params.RequiredDocumentTypes) {
+		reasons = append(reasons, "missing required document "+documentType)
+	}
+	return reasons
+}
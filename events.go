@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	// eventName Name every chaincode event is emitted under
+	eventName        = "evtSender"
+	eventsEnabledKey = "eventsEnabled"
+	// eventSchemaVersion Shape of the emitted event JSON; bump whenever fields are added, removed or change meaning
+	eventSchemaVersion = 1
+)
+
+// eventsEnabled Report whether per-record events are switched on, defaulting to on
+func eventsEnabled(stub shim.ChaincodeStubInterface) (bool, error) {
+	enabled := true
+	_, err := getConfig(stub, eventsEnabledKey, &enabled)
+	return enabled, err
+}
+
+// maxEventPayloadBytes Largest event payload emitted as is; larger ones are trimmed
+// so the transaction does not fail on an oversized event
+const maxEventPayloadBytes = 64 * 1024
+
+// emitEvent Marshal a custom event and emit it on the transaction, trimming it to
+// its type and ID with a truncated flag when it exceeds the payload size cap
+func emitEvent(stub shim.ChaincodeStubInterface, evt customEvent) error {
+	evt.SchemaVersion = eventSchemaVersion
+	if loanApplication, ok := evt.Payload.(*LoanApplication); ok && evt.ID == "" {
+		evt.ID = loanApplication.ID
+	}
+	evtBytes, err := json.Marshal(&evt)
+	if err != nil {
+		logger.Error("Could not marshal "+evt.Type+" event", err)
+		return err
+	}
+	if len(evtBytes) > maxEventPayloadBytes {
+		logger.Warning("Truncating oversized " + evt.Type + " event of " + strconv.Itoa(len(evtBytes)) + " bytes")
+		evtBytes, err = json.Marshal(&customEvent{SchemaVersion: evt.SchemaVersion, Type: evt.Type, ID: evt.ID, Truncated: true})
+		if err != nil {
+			return err
+		}
+	}
+	return stub.SetEvent(eventName, evtBytes)
+}
+
+// sendEvent Emit a per-record event unless events have been disabled
+func sendEvent(stub shim.ChaincodeStubInterface, eventType string, description string, payload interface{}) error {
+	enabled, err := eventsEnabled(stub)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		logger.Debug("Events disabled, skipping " + eventType + " event")
+		return nil
+	}
+	return emitEvent(stub, customEvent{Type: eventType, Decription: description, Payload: payload})
+}
+
+// sendSummaryEvent Emit the single summary event of a batch handler, even when per-record events are disabled
+func sendSummaryEvent(stub shim.ChaincodeStubInterface, eventType string, description string, payload interface{}) error {
+	return emitEvent(stub, customEvent{Type: eventType, Decription: description, Payload: payload})
+}
+
+// SetEventsEnabled Turn per-record events on or off with args[0] true or false
+func SetEventsEnabled(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetEventsEnabled")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected true or false")
+	}
+
+	enabled, err := strconv.ParseBool(args[0])
+	if err != nil {
+		return nil, errors.New("Expected true or false, got " + args[0])
+	}
+
+	err = putConfig(stub, eventsEnabledKey, enabled)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Events enabled set to " + strconv.FormatBool(enabled))
+	return nil, nil
+}
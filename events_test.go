@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSetEventsEnabledSuppressesEvents(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetEventsEnabled", "false")
+
+	createApplication(t, stub, testApplication("LA-1"))
+	stub.mustInvoke(t, "UpdateLoanApplication", "LA-1", statusUnderReview)
+	if len(stub.events) != 0 {
+		t.Fatalf("expected no events while disabled, got %d", len(stub.events))
+	}
+
+	stub.mustInvoke(t, "SetEventsEnabled", "true")
+	stub.mustInvoke(t, "UpdateLoanApplication", "LA-1", statusSubmitted)
+	if len(stub.events) != 1 {
+		t.Fatalf("expected an event once re-enabled, got %d", len(stub.events))
+	}
+}
+
+func TestOversizedEventIsTruncated(t *testing.T) {
+	stub := newMockStub()
+	rejected := testApplication("LA-1")
+	rejected.Status = statusRejected
+	rejected.Notes = []Note{{Text: strings.Repeat("x", maxEventPayloadBytes), Visibility: visibilityInternal}}
+	seedApplication(t, stub, rejected)
+
+	stub.mustInvoke(t, "ArchiveLoanApplication", "LA-1")
+
+	event := stub.lastEvent(t)
+	if len(event.payload) > maxEventPayloadBytes {
+		t.Fatalf("expected the event to fit the cap, got %d bytes", len(event.payload))
+	}
+	var evt customEvent
+	err := json.Unmarshal(event.payload, &evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !evt.Truncated || evt.Type != "archiveRequested" || evt.ID != "LA-1" || evt.Payload != nil {
+		t.Fatalf("expected a truncated archiveRequested event for LA-1, got %+v", evt)
+	}
+	if storedApplication(t, stub, "LA-1").Status != statusArchived {
+		t.Fatal("expected the archive to succeed despite the oversized event")
+	}
+}
+
+func TestEventsCarrySchemaVersion(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+	stub.mustInvoke(t, "UpdateLoanApplication", "LA-1", statusUnderReview)
+	stub.mustInvoke(t, "SetEventsEnabled", "false")
+	stub.mustInvoke(t, "SetLoanParameters", `{"piiRetentionDays":30}`)
+	stub.mustInvoke(t, "PurgePII")
+
+	if len(stub.events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(stub.events))
+	}
+	for _, event := range stub.events {
+		var fields map[string]interface{}
+		err := json.Unmarshal(event.payload, &fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.name != eventName || fields["schemaVersion"] != float64(eventSchemaVersion) {
+			t.Errorf("expected schema version %d on %s event, got %v", eventSchemaVersion, fields["type"], fields["schemaVersion"])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// enabledFunctionsKey Ledger key of the admin-managed allow-list of callable functions
+const enabledFunctionsKey = "enabledFunctions"
+
+// allowListFunction Always callable so an admin cannot lock the allow-list itself
+const allowListFunction = "SetEnabledFunctions"
+
+// ensureFunctionEnabled Reject functions missing from the allow-list; with no list set every function is enabled
+func ensureFunctionEnabled(stub shim.ChaincodeStubInterface, function string) error {
+	if function == allowListFunction {
+		return nil
+	}
+	var enabled []string
+	found, err := getConfig(stub, enabledFunctionsKey, &enabled)
+	if err != nil || !found {
+		return err
+	}
+	for _, name := range enabled {
+		if name == function {
+			return nil
+		}
+	}
+	logger.Warning("Rejected call to disabled function " + function)
+	return errors.New("Function " + function + " is disabled")
+}
+
+// SetEnabledFunctions Replace the allow-list with the JSON array of function names in args[0], or remove it
+// so every function is enabled when args[0] is null
+func SetEnabledFunctions(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetEnabledFunctions")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected enabled functions argument")
+	}
+
+	var enabled []string
+	err := json.Unmarshal([]byte(args[0]), &enabled)
+	if err != nil {
+		logger.Error("Could not unmarshal enabled functions", err)
+		return nil, errors.New("Invalid enabled functions: " + err.Error())
+	}
+
+	if enabled == nil {
+		err = stub.DelState(enabledFunctionsKey)
+		if err != nil {
+			logger.Error("Could not delete enabled functions", err)
+			return nil, err
+		}
+		logger.Info("Removed function allow-list")
+		return nil, nil
+	}
+
+	err = putConfig(stub, enabledFunctionsKey, enabled)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully saved enabled functions")
+	return nil, nil
+}
+
+// GetEnabledFunctions Get the allow-list of callable functions, null when every function is enabled
+func GetEnabledFunctions(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetEnabledFunctions")
+
+	var enabled []string
+	_, err := getConfig(stub, enabledFunctionsKey, &enabled)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(enabled)
+}
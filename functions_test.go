@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisabledFunctionIsRejected(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	stub.mustInvoke(t, "SetEnabledFunctions", `["AddTag","GetLoanApplication"]`)
+	_, err := stub.invoke("UpdateLoanApplication", "LA-1", statusUnderReview)
+	if err == nil || !strings.Contains(err.Error(), "Function UpdateLoanApplication is disabled") {
+		t.Fatalf("expected the update to be disabled, got %v", err)
+	}
+	if status := storedApplication(t, stub, "LA-1").Status; status != statusSubmitted {
+		t.Fatalf("expected the disabled update not to run, got %s", status)
+	}
+	if _, err := stub.query("ListAllLoanApplications"); err == nil {
+		t.Fatal("expected queries missing from the allow-list to be disabled")
+	}
+	stub.mustInvoke(t, "AddTag", "LA-1", "fast-track")
+	stub.mustQuery(t, "GetLoanApplication", "LA-1")
+
+	// The allow-list itself stays callable, and null removes it
+	stub.mustInvoke(t, "SetEnabledFunctions", `null`)
+	stub.mustInvoke(t, "UpdateLoanApplication", "LA-1", statusUnderReview)
+	if status := storedApplication(t, stub, "LA-1").Status; status != statusUnderReview {
+		t.Fatalf("expected the update to run once re-enabled, got %s", status)
+	}
+	if _, err := stub.as("rev1", roleReviewer).invoke("SetEnabledFunctions", `[]`); err == nil {
+		t.Fatal("expected non-admins to be denied")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// FX rates in the loan parameters are units of each currency per one unit of the default currency
+
+// validateFXRates Ensure the default currency and cross-rate table are usable
+func validateFXRates(params LoanParameters) error {
+	if params.DefaultCurrency != "" && !validCurrencies[params.DefaultCurrency] {
+		return errors.New("Unsupported default currency '" + params.DefaultCurrency + "'")
+	}
+	if len(params.FXRates) > 0 && params.DefaultCurrency == "" {
+		return errors.New("FX rates require a default currency to be quoted against")
+	}
+	for currency, rate := range params.FXRates {
+		if !validCurrencies[currency] {
+			return errors.New("Unsupported FX rate currency '" + currency + "'")
+		}
+		if rate <= 0 {
+			return errors.New("FX rate for " + currency + " must be positive")
+		}
+	}
+	return nil
+}
+
+// fxRate Units of a currency per unit of the default currency
+func (p LoanParameters) fxRate(currency string) (float64, error) {
+	if currency == p.DefaultCurrency {
+		return 1, nil
+	}
+	rate, ok := p.FXRates[currency]
+	if !ok {
+		return 0, errors.New("No FX rate from " + p.DefaultCurrency + " to " + currency)
+	}
+	return rate, nil
+}
+
+// convert Convert an amount between currencies through the default currency, treating an
+// empty currency as the default and rounding under the configured policy
+func (p LoanParameters) convert(amount int64, from, to string) (int64, error) {
+	if from == "" {
+		from = p.DefaultCurrency
+	}
+	if to == "" {
+		to = p.DefaultCurrency
+	}
+	if from == to {
+		return amount, nil
+	}
+
+	fromRate, err := p.fxRate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := p.fxRate(to)
+	if err != nil {
+		return 0, err
+	}
+	converted := float64(amount) / fromRate * toRate
+	if math.Abs(converted) >= math.MaxInt64 {
+		return 0, errMonetaryOverflow
+	}
+	return roundMoney(converted, p.RoundingPolicy), nil
+}
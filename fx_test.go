@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertThroughDefaultCurrency(t *testing.T) {
+	params := LoanParameters{DefaultCurrency: "USD", FXRates: map[string]float64{"EUR": 0.9, "GBP": 0.8}}
+
+	cases := []struct {
+		amount   int64
+		from, to string
+		expected int64
+	}{
+		{900, "EUR", "USD", 1000},
+		{1000, "USD", "GBP", 800},
+		{900, "EUR", "GBP", 800},
+		{1000, "", "EUR", 900},
+		{1000, "GBP", "GBP", 1000},
+		{5, "USD", "EUR", 5},
+	}
+	for _, c := range cases {
+		converted, err := params.convert(c.amount, c.from, c.to)
+		if err != nil || converted != c.expected {
+			t.Errorf("expected %d %s to be %d %s, got %d, %v", c.amount, c.from, c.expected, c.to, converted, err)
+		}
+	}
+
+	params.RoundingPolicy = roundingFloor
+	if converted, _ := params.convert(5, "USD", "EUR"); converted != 4 {
+		t.Errorf("expected 4.5 to round down to 4 under floor, got %d", converted)
+	}
+}
+
+func TestConvertRejectsMissingRate(t *testing.T) {
+	params := LoanParameters{DefaultCurrency: "USD", FXRates: map[string]float64{"EUR": 0.9}}
+
+	for _, pair := range [][]string{{"JPY", "USD"}, {"USD", "JPY"}, {"EUR", "JPY"}} {
+		if _, err := params.convert(1000, pair[0], pair[1]); err == nil {
+			t.Errorf("expected converting %s to %s without a rate to be rejected", pair[0], pair[1])
+		}
+	}
+}
+
+func TestLoanToValueAcrossCurrencies(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"maxLoanToValue":0.75,"defaultCurrency":"USD","fxRates":{"EUR":0.9}}`)
+	converted := testApplication("LA-1")
+	converted.RequestedAmount = 400000
+	converted.FairMarketValue = 450000
+	converted.FairMarketValueCurrency = "EUR"
+	seedApplication(t, stub, converted)
+	missingRate := testApplication("LA-2")
+	missingRate.FairMarketValueCurrency = "JPY"
+	seedApplication(t, stub, missingRate)
+
+	var summary loanApplicationSummary
+	err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationSummary", "LA-1"), &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.LoanToValue != 0.8 || summary.Eligible || summary.Reasons[0] != "loan-to-value 0.80 exceeds maximum 0.75" {
+		t.Fatalf("expected the EUR value to convert to 500000 USD for an LTV of 0.8, got %+v", summary)
+	}
+
+	err = json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationSummary", "LA-2"), &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Eligible || len(summary.Reasons) != 1 || summary.Reasons[0] != "cannot assess loan-to-value: No FX rate from USD to JPY" {
+		t.Fatalf("expected a missing FX rate to block the LTV check, got %+v", summary)
+	}
+	if _, err := stub.invoke("SetLoanParameters", `{"fxRates":{"EUR":0.9}}`); err == nil {
+		t.Fatal("expected FX rates without a default currency to be rejected")
+	}
+}
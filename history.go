@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// This shim has no GetHistoryForKey, so every save also records a snapshot of the
+// application under a composite key ordered by transaction time
+const (
+	historyIndexName = "history~id"
+	// Fixed width so snapshot keys sort chronologically
+	historyTimestampLayout = "2006-01-02T15:04:05.000000000Z"
+)
+
+// historyEntry One recorded version of a loan application
+type historyEntry struct {
+	TxID      string          `json:"txId"`
+	Timestamp string          `json:"timestamp"`
+	Value     LoanApplication `json:"value"`
+}
+
+// fieldChange The before and after values of a changed field
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// recordHistory Save a snapshot of the application as written by this transaction
+func recordHistory(stub shim.ChaincodeStubInterface, loanApplication *LoanApplication, now time.Time) error {
+	entry := historyEntry{
+		TxID:      stub.GetTxID(),
+		Timestamp: now.Format(historyTimestampLayout),
+		Value:     *loanApplication,
+	}
+	entryBytes, err := marshalState(&entry)
+	if err != nil {
+		logger.Error("Could not marshal history entry for "+loanApplication.ID, err)
+		return err
+	}
+	key, err := createCompositeKey(historyIndexName, []string{loanApplication.ID, entry.Timestamp, entry.TxID})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, entryBytes)
+}
+
+// getLoanApplicationHistory Load every recorded version of an application, oldest first
+func getLoanApplicationHistory(stub shim.ChaincodeStubInterface, loanAppID string) ([]historyEntry, error) {
+	keys, err := getKeysByPartialCompositeKey(stub, historyIndexName, []string{loanAppID})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []historyEntry{}
+	for _, key := range keys {
+		entryBytes, err := stub.GetState(key)
+		if err != nil {
+			logger.Error("Could not fetch history entry for "+loanAppID, err)
+			return nil, err
+		}
+		var entry historyEntry
+		err = json.Unmarshal(entryBytes, &entry)
+		if err != nil {
+			logger.Error("Could not unmarshal history entry for "+loanAppID, err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// flattenFields Flatten nested JSON objects into dotted field paths
+func flattenFields(prefix string, value interface{}, fields map[string]interface{}) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		fields[prefix] = value
+		return
+	}
+	for name, child := range object {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		flattenFields(path, child, fields)
+	}
+}
+
+// renderedFields Flatten a rendered application into dotted field paths
+func renderedFields(record json.RawMessage) (map[string]interface{}, error) {
+	var generic interface{}
+	err := json.Unmarshal(record, &generic)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	flattenFields("", generic, fields)
+	return fields, nil
+}
+
+// diffLoanApplications Map each field that differs between two rendered versions to its old and new value
+func diffLoanApplications(from, to json.RawMessage) (map[string]fieldChange, error) {
+	fromFields, err := renderedFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := renderedFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]fieldChange{}
+	for field, fromValue := range fromFields {
+		if toValue := toFields[field]; !reflect.DeepEqual(fromValue, toValue) {
+			changes[field] = fieldChange{From: fromValue, To: toValue}
+		}
+	}
+	for field, toValue := range toFields {
+		if _, ok := fromFields[field]; !ok {
+			changes[field] = fieldChange{From: nil, To: toValue}
+		}
+	}
+	return changes, nil
+}
+
+// GetLoanApplicationDiff Get the fields of application args[0] that changed between transactions args[1] and args[2].
+// Both versions are redacted as the caller would see them before comparing.
+func GetLoanApplicationDiff(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationDiff")
+
+	if len(args) < 3 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID and two transaction IDs")
+	}
+
+	var loanAppID = args[0]
+
+	entries, err := getLoanApplicationHistory(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions [2]*historyEntry
+	for i, txID := range args[1:3] {
+		for j := range entries {
+			if entries[j].TxID == txID {
+				versions[i] = &entries[j]
+				break
+			}
+		}
+		if versions[i] == nil {
+			return nil, errors.New("No version of loan application " + loanAppID + " written by transaction " + txID)
+		}
+	}
+
+	rendered, err := viewLoanApplications(stub, []LoanApplication{versions[0].Value, versions[1].Value}, nil)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := diffLoanApplications(rendered[0], rendered[1])
+	if err != nil {
+		logger.Error("Could not diff loan application versions", err)
+		return nil, err
+	}
+	return json.Marshal(changes)
+}
+
+// earliestChangedBy Find who made the earliest recorded status change of an application, checking the
+// oldest history snapshot before the application's own status history
+func earliestChangedBy(stub shim.ChaincodeStubInterface, loanApplication LoanApplication) (string, error) {
+	entries, err := getLoanApplicationHistory(stub, loanApplication.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if own := ownStatusHistory(entry.Value); len(own) > 0 && own[0].ChangedBy != "" {
+			return own[0].ChangedBy, nil
+		}
+	}
+	if own := ownStatusHistory(loanApplication); len(own) > 0 {
+		return own[0].ChangedBy, nil
+	}
+	return "", nil
+}
+
+// backfillResult Outcome of BackfillCreatedBy
+type backfillResult struct {
+	Backfilled int      `json:"backfilled"`
+	Unresolved []string `json:"unresolved"`
+	Locked     []string `json:"locked"`
+}
+
+// BackfillCreatedBy Set CreatedBy on applications saved before it was recorded, taking the user behind
+// the earliest recorded status change. The shim has no GetHistoryForKey, so applications with no
+// recorded change are reported as unresolved, and locked applications are skipped and reported.
+func BackfillCreatedBy(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering BackfillCreatedBy")
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	result := backfillResult{Unresolved: []string{}, Locked: []string{}}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.CreatedBy != "" {
+			continue
+		}
+		if loanApplication.Locked {
+			result.Locked = append(result.Locked, loanApplication.ID)
+			continue
+		}
+		creator, err := earliestChangedBy(stub, loanApplication)
+		if err != nil {
+			return nil, err
+		}
+		if creator == "" {
+			result.Unresolved = append(result.Unresolved, loanApplication.ID)
+			continue
+		}
+
+		loanApplication.CreatedBy = creator
+		// Keep the modification date so expiry, SLA and retention clocks are not reset
+		err = putLoanApplication(stub, &loanApplication, now)
+		if err != nil {
+			return nil, err
+		}
+		err = putIndexEntry(stub, creatorIndexName, []string{creator, loanApplication.ID})
+		if err != nil {
+			return nil, err
+		}
+		result.Backfilled++
+	}
+
+	err = sendSummaryEvent(stub, "createdByBackfilled", "Backfilled creator of "+strconv.Itoa(result.Backfilled)+" loan applications", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Backfilled creator of " + strconv.Itoa(result.Backfilled) + " loan applications")
+	return json.Marshal(result)
+}
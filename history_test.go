@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestGetLoanApplicationDiffStatusOnly(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+	created := stub.txID
+	stub.now = stub.now.Add(time.Hour)
+	stub.mustInvoke(t, "UpdateLoanApplication", "LA-1", statusUnderReview)
+	updated := stub.txID
+
+	var changes map[string]fieldChange
+	err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationDiff", "LA-1", created, updated), &changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected only status, statusHistory and lastModifiedDate to change, got %v", changes)
+	}
+	if changes["status"].From != statusSubmitted || changes["status"].To != statusUnderReview {
+		t.Fatalf("expected status Submitted to UnderReview, got %+v", changes["status"])
+	}
+	for _, field := range []string{"statusHistory", "lastModifiedDate"} {
+		if _, ok := changes[field]; !ok {
+			t.Errorf("expected %s to change", field)
+		}
+	}
+}
+
+func TestGetLoanApplicationDiffUnknownTransaction(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+
+	if _, err := stub.query("GetLoanApplicationDiff", "LA-1", stub.txID, "tx-missing"); err == nil {
+		t.Fatal("expected a transaction with no version to be rejected")
+	}
+}
+
+func TestBackfillCreatedByFromEarliestChange(t *testing.T) {
+	stub := newMockStub()
+	legacy := testApplication("LA-1")
+	legacy.LastModifiedDate = stub.now.AddDate(0, 0, -10).Format(time.RFC3339)
+	legacy.StatusHistory = []StatusChange{{Status: statusSubmitted, Date: legacy.LastModifiedDate, ChangedBy: "officer1"}}
+	seedApplication(t, stub, legacy)
+	legacy.Status = statusUnderReview
+	legacy.StatusHistory = append(legacy.StatusHistory, StatusChange{Status: statusUnderReview, Date: legacy.LastModifiedDate, ChangedBy: "admin"})
+	seedApplication(t, stub, legacy)
+	seedApplication(t, stub, testApplication("LA-2"))
+	owned := testApplication("LA-3")
+	owned.CreatedBy = "officer2"
+	owned.StatusHistory = []StatusChange{{Status: statusSubmitted, ChangedBy: "someone-else"}}
+	seedApplication(t, stub, owned)
+	locked := testApplication("LA-4")
+	locked.Locked = true
+	locked.StatusHistory = legacy.StatusHistory[:1]
+	seedApplication(t, stub, locked)
+
+	var result backfillResult
+	err := json.Unmarshal(stub.mustInvoke(t, "BackfillCreatedBy"), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Backfilled != 1 || len(result.Unresolved) != 1 || result.Unresolved[0] != "LA-2" || len(result.Locked) != 1 || result.Locked[0] != "LA-4" {
+		t.Fatalf("expected LA-1 backfilled, LA-2 unresolved and LA-4 locked, got %+v", result)
+	}
+	backfilled := storedApplication(t, stub, "LA-1")
+	if backfilled.CreatedBy != "officer1" || backfilled.LastModifiedDate != legacy.LastModifiedDate {
+		t.Fatalf("expected officer1 as creator with the modification date kept, got %s at %s", backfilled.CreatedBy, backfilled.LastModifiedDate)
+	}
+	if creator := storedApplication(t, stub, "LA-3").CreatedBy; creator != "officer2" {
+		t.Fatalf("expected an existing creator to be kept, got %s", creator)
+	}
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByCreatedByUser", "officer1")), "LA-1")
+	if _, err := stub.as("auditor", roleAuditor).invoke("BackfillCreatedBy"); err == nil {
+		t.Fatal("expected non-admins to be denied")
+	}
+}
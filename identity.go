@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const certAttributeFallbackKey = "certAttributeFallback"
+
+// certAttributeFallbackEnabled Report whether missing cert attributes may be derived from the caller certificate
+func certAttributeFallbackEnabled(stub shim.ChaincodeStubInterface) bool {
+	var enabled bool
+	_, err := getConfig(stub, certAttributeFallbackKey, &enabled)
+	return err == nil && enabled
+}
+
+// creatorAttribute Derive username, role or org from the subject of the caller certificate
+func creatorAttribute(stub shim.ChaincodeStubInterface, attributeName string) (string, error) {
+	certBytes, err := stub.GetCallerCertificate()
+	if err != nil {
+		return "", err
+	}
+	if block, _ := pem.Decode(certBytes); block != nil {
+		certBytes = block.Bytes
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return "", err
+	}
+
+	subject := cert.Subject
+	switch attributeName {
+	case "username":
+		if subject.CommonName != "" {
+			return subject.CommonName, nil
+		}
+	case "role":
+		if len(subject.OrganizationalUnit) > 0 {
+			return subject.OrganizationalUnit[0], nil
+		}
+	case "org":
+		if len(subject.Organization) > 0 {
+			return subject.Organization[0], nil
+		}
+	}
+	return "", errors.New("Caller certificate has no " + attributeName)
+}
+
+// SetCertAttributeFallback Turn the caller certificate fallback for missing attributes on or off with args[0]
+func SetCertAttributeFallback(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetCertAttributeFallback")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected true or false")
+	}
+
+	enabled, err := strconv.ParseBool(args[0])
+	if err != nil {
+		return nil, errors.New("Expected true or false, got " + args[0])
+	}
+
+	err = putConfig(stub, certAttributeFallbackKey, enabled)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Cert attribute fallback set to " + strconv.FormatBool(enabled))
+	return nil, nil
+}
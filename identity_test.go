@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testCertificate A self-signed PEM certificate with the given common name and organizational unit
+func testCertificate(t *testing.T, commonName string, unit string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName, OrganizationalUnit: []string{unit}, Organization: []string{"Bank"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestGetCertAttributePresent(t *testing.T) {
+	stub := newMockStub().as("alice", roleReviewer)
+	stub.cert = testCertificate(t, "mallory", roleAdmin)
+	stub.state[certAttributeFallbackKey] = []byte("true")
+
+	username, err := GetCertAttribute(stub, "username")
+	if err != nil || username != "alice" {
+		t.Fatalf("expected the attribute to win over the certificate, got %q, %v", username, err)
+	}
+	role, _ := GetCertAttribute(stub, "role")
+	if role != roleReviewer {
+		t.Fatalf("expected role %s, got %s", roleReviewer, role)
+	}
+}
+
+func TestGetCertAttributeFallback(t *testing.T) {
+	stub := newMockStub()
+	stub.attrs = map[string]string{}
+	stub.cert = testCertificate(t, "carol", roleAdmin)
+
+	if _, err := GetCertAttribute(stub, "role"); err == nil {
+		t.Fatal("expected a missing attribute to fail while the fallback is disabled")
+	}
+	if _, err := stub.invoke("SetLoanParameters", `{}`); err == nil {
+		t.Fatal("expected an admin-only call to be denied without attributes")
+	}
+
+	if _, err := new(SampleChainCode).Init(stub, "init", []string{"true"}); err != nil {
+		t.Fatal(err)
+	}
+	username, err := GetCertAttribute(stub, "username")
+	if err != nil || username != "carol" {
+		t.Fatalf("expected the certificate common name, got %q, %v", username, err)
+	}
+	stub.mustInvoke(t, "SetLoanParameters", `{}`)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	buyerIndexName    = "buyer~id"
+	landIndexName     = "land~id"
+	propertyIndexName = "property~id"
+	tagIndexName      = "tag~id"
+	creatorIndexName  = "creator~id"
+)
+
+// loanIndex A composite key index of loan applications by a derived attribute
+type loanIndex struct {
+	name   string
+	values func(loanApplication LoanApplication) []string
+}
+
+// loanIndexes Every index maintained over loan applications
+var loanIndexes = []loanIndex{
+	{reviewerIndexName, func(loanApplication LoanApplication) []string {
+		if loanApplication.ReviewerID == "" || loanApplication.ReviewerID == systemReviewerID {
+			return nil
+		}
+		return []string{loanApplication.ReviewerID}
+	}},
+	{buyerIndexName, func(loanApplication LoanApplication) []string {
+		if loanApplication.BuyerID == "" {
+			return nil
+		}
+		return []string{loanApplication.BuyerID}
+	}},
+	{landIndexName, func(loanApplication LoanApplication) []string {
+		if loanApplication.LandID == "" {
+			return nil
+		}
+		return []string{loanApplication.LandID}
+	}},
+	{propertyIndexName, func(loanApplication LoanApplication) []string {
+		if loanApplication.PropertyID == "" {
+			return nil
+		}
+		return []string{loanApplication.PropertyID}
+	}},
+	{tagIndexName, func(loanApplication LoanApplication) []string {
+		return loanApplication.Tags
+	}},
+	{creatorIndexName, func(loanApplication LoanApplication) []string {
+		if loanApplication.CreatedBy == "" {
+			return nil
+		}
+		return []string{loanApplication.CreatedBy}
+	}},
+}
+
+// getLoanApplicationsByIndex Load every application indexed under a value
+func getLoanApplicationsByIndex(stub shim.ChaincodeStubInterface, indexName string, value string) ([]LoanApplication, error) {
+	keys, err := getKeysByPartialCompositeKey(stub, indexName, []string{value})
+	if err != nil {
+		return nil, err
+	}
+
+	loanApplications := []LoanApplication{}
+	for _, key := range keys {
+		_, attributes := splitCompositeKey(key)
+		if len(attributes) < 2 {
+			continue
+		}
+		loanApplication, err := getLoanApplication(stub, attributes[1])
+		if err != nil {
+			logger.Warning("Skipping stale " + indexName + " entry for " + attributes[1])
+			continue
+		}
+		loanApplications = append(loanApplications, loanApplication)
+	}
+	return loanApplications, nil
+}
+
+// addLoanIndexes Write every index entry derived from an application
+func addLoanIndexes(stub shim.ChaincodeStubInterface, loanApplication LoanApplication) error {
+	for _, index := range loanIndexes {
+		for _, value := range index.values(loanApplication) {
+			err := putIndexEntry(stub, index.name, []string{value, loanApplication.ID})
+			if err != nil {
+				logger.Error("Could not save "+index.name+" entry for "+loanApplication.ID, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeLoanIndexes Delete every index entry derived from an application
+func removeLoanIndexes(stub shim.ChaincodeStubInterface, loanApplication LoanApplication) error {
+	for _, index := range loanIndexes {
+		for _, value := range index.values(loanApplication) {
+			err := delIndexEntry(stub, index.name, []string{value, loanApplication.ID})
+			if err != nil {
+				logger.Error("Could not delete "+index.name+" entry for "+loanApplication.ID, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RebuildIndexes Drop and recreate every loan application index from current record state
+func RebuildIndexes(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering RebuildIndexes")
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := 0
+	for _, index := range loanIndexes {
+		keys, err := getKeysByPartialCompositeKey(stub, index.name, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			err = stub.DelState(key)
+			if err != nil {
+				logger.Error("Could not delete index key from "+index.name, err)
+				return nil, err
+			}
+		}
+
+		for _, loanApplication := range loanApplications {
+			for _, value := range index.values(loanApplication) {
+				err = putIndexEntry(stub, index.name, []string{value, loanApplication.ID})
+				if err != nil {
+					logger.Error("Could not recreate "+index.name+" entry for "+loanApplication.ID, err)
+					return nil, err
+				}
+				entries++
+			}
+		}
+	}
+
+	err = sendSummaryEvent(stub, "indexesRebuilt", "Rebuilt indexes with "+strconv.Itoa(entries)+" entries", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Rebuilt loan application indexes with " + strconv.Itoa(entries) + " entries")
+	return []byte(strconv.Itoa(entries)), nil
+}
+
+// GetLoanApplicationsByLandID Get applications secured against land parcel args[0]
+func GetLoanApplicationsByLandID(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsByLandID")
+
+	if len(args) < 1 || args[0] == "" {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing land ID")
+	}
+
+	loanApplications, err := getLoanApplicationsByIndex(stub, landIndexName, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalLoanApplications(stub, loanApplications)
+}
+
+// propertyExposure Total approved exposure secured against a property
+type propertyExposure struct {
+	PropertyID    string `json:"PropertyID"`
+	TotalExposure int64  `json:"totalExposure"`
+	Count         int    `json:"count"`
+}
+
+// noExposureStatuses Statuses of applications whose approved amount will never be drawn
+var noExposureStatuses = map[string]bool{
+	statusRejected:        true,
+	statusArchived:        true,
+	statusExpired:         true,
+	statusMerged:          true,
+	statusApprovalExpired: true,
+}
+
+// GetTotalExposureByProperty Get the approved amount across applications on property args[0] that are
+// still live or have been disbursed
+func GetTotalExposureByProperty(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetTotalExposureByProperty")
+
+	if len(args) < 1 || args[0] == "" {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing property ID")
+	}
+
+	loanApplications, err := getLoanApplicationsByIndex(stub, propertyIndexName, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	exposure := propertyExposure{PropertyID: args[0]}
+	for _, loanApplication := range loanApplications {
+		if noExposureStatuses[loanApplication.Status] {
+			continue
+		}
+		exposure.TotalExposure, err = addMoney(exposure.TotalExposure, loanApplication.ApprovedAmount)
+		if err != nil {
+			return nil, err
+		}
+		exposure.Count++
+	}
+	return json.Marshal(&exposure)
+}
+
+// GetApplicationsByCreatedByUser Get the applications created by user args[0]. Applications saved before
+// the creator index existed are found once RebuildIndexes has run.
+func GetApplicationsByCreatedByUser(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationsByCreatedByUser")
+
+	if len(args) < 1 || args[0] == "" {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing creator username")
+	}
+
+	loanApplications, err := getLoanApplicationsByIndex(stub, creatorIndexName, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalLoanApplications(stub, loanApplications)
+}
+
+// GetApplicationsByBuyers Get the applications of every buyer in the JSON array of buyer IDs args[0]
+func GetApplicationsByBuyers(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationsByBuyers")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing buyer IDs")
+	}
+
+	var buyerIDs []string
+	err := json.Unmarshal([]byte(args[0]), &buyerIDs)
+	if err != nil {
+		logger.Error("Could not unmarshal buyer IDs", err)
+		return nil, errors.New("Invalid buyer IDs: " + err.Error())
+	}
+
+	seen := map[string]bool{}
+	matches := []LoanApplication{}
+	for _, buyerID := range buyerIDs {
+		if buyerID == "" {
+			continue
+		}
+		loanApplications, err := getLoanApplicationsByIndex(stub, buyerIndexName, buyerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, loanApplication := range loanApplications {
+			if !seen[loanApplication.ID] {
+				seen[loanApplication.ID] = true
+				matches = append(matches, loanApplication)
+			}
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRebuildIndexesRestoresLookup(t *testing.T) {
+	stub := newMockStub()
+	for _, id := range []string{"LA-1", "LA-2"} {
+		loanApplication := testApplication(id)
+		loanApplication.LandID = "LAND-1"
+		seedApplication(t, stub, loanApplication)
+	}
+
+	// Lose one entry and leave a stale one pointing at a land parcel the application is no longer on
+	if err := delIndexEntry(stub, landIndexName, []string{"LAND-1", "LA-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := putIndexEntry(stub, landIndexName, []string{"LAND-9", "LA-1"}); err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByLandID", "LAND-1")), "LA-1")
+
+	stub.mustInvoke(t, "RebuildIndexes")
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByLandID", "LAND-1")), "LA-1", "LA-2")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByLandID", "LAND-9")))
+	if event := stub.lastEvent(t); event.name != eventName {
+		t.Fatalf("expected a summary event, got %s", event.name)
+	}
+}
+
+func TestGetLoanApplicationsByLandIDMultiple(t *testing.T) {
+	stub := newMockStub()
+	for _, id := range []string{"LA-1", "LA-2", "LA-3"} {
+		loanApplication := testApplication(id)
+		loanApplication.LandID = "LAND-1"
+		createApplication(t, stub, loanApplication)
+	}
+	createApplication(t, stub, testApplication("LA-4"))
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByLandID", "LAND-1")), "LA-1", "LA-2", "LA-3")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByLandID", "LAND-2")))
+}
+
+func TestGetTotalExposureByProperty(t *testing.T) {
+	stub := newMockStub()
+	seeds := []struct {
+		id       string
+		status   string
+		approved int64
+	}{
+		{"LA-1", statusApproved, 200000},
+		{"LA-2", statusDisbursed, 150000},
+		{"LA-3", statusRejected, 300000},
+		{"LA-4", statusSubmitted, 0},
+		{"LA-6", statusApprovalExpired, 250000},
+		{"LA-7", statusMerged, 100000},
+		{"LA-8", statusArchived, 120000},
+	}
+	for _, seed := range seeds {
+		loanApplication := testApplication(seed.id)
+		loanApplication.PropertyID = "PROP-1"
+		loanApplication.Status = seed.status
+		loanApplication.ApprovedAmount = seed.approved
+		seedApplication(t, stub, loanApplication)
+	}
+	seedApproved(t, stub, "LA-5", 999999)
+
+	var exposure propertyExposure
+	err := json.Unmarshal(stub.mustQuery(t, "GetTotalExposureByProperty", "PROP-1"), &exposure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exposure.TotalExposure != 350000 || exposure.Count != 3 {
+		t.Fatalf("expected 350000 across 3 applications, got %+v", exposure)
+	}
+}
+
+func TestGetApplicationsByCreatedByUser(t *testing.T) {
+	stub := newMockStub()
+	for id, creator := range map[string]string{"LA-1": "officer1", "LA-2": "officer2", "LA-3": "officer1"} {
+		input, _ := json.Marshal(testApplication(id))
+		stub.as(creator, roleAdmin).mustInvoke(t, "CreateLoanApplication", id, string(input))
+	}
+
+	stub.as("auditor", roleAuditor)
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByCreatedByUser", "officer1")), "LA-1", "LA-3")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByCreatedByUser", "officer2")), "LA-2")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByCreatedByUser", "officer3")))
+	if _, err := stub.as("rev1", roleReviewer).query("GetApplicationsByCreatedByUser", "officer1"); err == nil {
+		t.Fatal("expected reviewers to be denied")
+	}
+}
+
+func TestGetApplicationsByBuyers(t *testing.T) {
+	stub := newMockStub()
+	for id, buyer := range map[string]string{"LA-1": "BUYER-A", "LA-2": "BUYER-B", "LA-3": "BUYER-A", "LA-4": "BUYER-C"} {
+		loanApplication := testApplication(id)
+		loanApplication.BuyerID = buyer
+		seedApplication(t, stub, loanApplication)
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByBuyers", `["BUYER-B","BUYER-A"]`)), "LA-2", "LA-1", "LA-3")
+	// A buyer listed twice contributes its applications once
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByBuyers", `["BUYER-A","BUYER-A",""]`)), "LA-1", "LA-3")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByBuyers", `["BUYER-Z"]`)))
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByBuyers", `[]`)))
+	if _, err := stub.query("GetApplicationsByBuyers", "BUYER-A"); err == nil {
+		t.Fatal("expected a non-array argument to be rejected")
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Loan application keys are namespaced so range scans skip other record types
+const (
+	loanKeyPrefix = "loan_"
+	loanKeyEnd    = "loan_~"
+)
+
+// marshalState Encode a value for PutState as canonical JSON, so every endorser writes byte-identical
+// state whatever the field or map ordering of the value
+func marshalState(v interface{}) ([]byte, error) {
+	plain, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalJSON(plain)
+}
+
+// loanKey Ledger key of the loan application with the given ID
+func loanKey(loanAppID string) string {
+	return loanKeyPrefix + loanAppID
+}
+
+// Generated application IDs are numbered from an on-ledger counter
+const (
+	loanIDCounterKey = "loanApplicationCounter"
+	loanIDFormat     = "LN-%06d"
+)
+
+// nextLoanApplicationID Increment the application counter and return the next unused generated ID.
+// The counter is ledger state, so every peer endorsing the transaction derives the same ID.
+func nextLoanApplicationID(stub shim.ChaincodeStubInterface) (string, error) {
+	var counter int64
+	_, err := getConfig(stub, loanIDCounterKey, &counter)
+	if err != nil {
+		return "", err
+	}
+	for {
+		counter++
+		loanAppID := fmt.Sprintf(loanIDFormat, counter)
+		// Skip numbers already taken by client supplied IDs
+		existing, err := stub.GetState(loanKey(loanAppID))
+		if err != nil {
+			logger.Error("Could not fetch loan application "+loanAppID+" from ledger", err)
+			return "", err
+		}
+		if existing == nil {
+			return loanAppID, putConfig(stub, loanIDCounterKey, counter)
+		}
+	}
+}
+
+// Composite keys mirror the layout used by later Fabric releases so indexes
+// can be range scanned by partial key
+const (
+	compositeKeyNamespace = "\x00"
+	minUnicodeRuneValue   = 0
+	maxUnicodeRuneValue   = utf8.MaxRune
+)
+
+// createCompositeKey Build an index key from an object type and its attributes
+func createCompositeKey(objectType string, attributes []string) (string, error) {
+	key := compositeKeyNamespace + objectType + string(rune(minUnicodeRuneValue))
+	for _, attribute := range attributes {
+		if strings.ContainsRune(attribute, minUnicodeRuneValue) {
+			return "", errors.New("Composite key attribute " + attribute + " contains an invalid character")
+		}
+		key += attribute + string(rune(minUnicodeRuneValue))
+	}
+	return key, nil
+}
+
+// splitCompositeKey Split an index key back into object type and attributes
+func splitCompositeKey(compositeKey string) (string, []string) {
+	components := strings.Split(strings.TrimPrefix(compositeKey, compositeKeyNamespace), string(rune(minUnicodeRuneValue)))
+	if len(components) == 0 {
+		return "", nil
+	}
+	// Trailing separator leaves an empty final component
+	return components[0], components[1 : len(components)-1]
+}
+
+// getKeysByPartialCompositeKey List all index keys starting with the given attributes
+func getKeysByPartialCompositeKey(stub shim.ChaincodeStubInterface, objectType string, attributes []string) ([]string, error) {
+	partialKey, err := createCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := stub.RangeQueryState(partialKey, partialKey+string(rune(maxUnicodeRuneValue)))
+	if err != nil {
+		logger.Error("Could not start range query over index "+objectType, err)
+		return nil, err
+	}
+	defer iter.Close()
+
+	var keys []string
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			logger.Error("Could not read next key from index "+objectType, err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// putIndexEntry Add an entry to a composite key index
+func putIndexEntry(stub shim.ChaincodeStubInterface, objectType string, attributes []string) error {
+	key, err := createCompositeKey(objectType, attributes)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, []byte{0x00})
+}
+
+// delIndexEntry Remove an entry from a composite key index
+func delIndexEntry(stub shim.ChaincodeStubInterface, objectType string, attributes []string) error {
+	key, err := createCompositeKey(objectType, attributes)
+	if err != nil {
+		return err
+	}
+	return stub.DelState(key)
+}
+
+// txTimestamp Get the transaction timestamp as a time
+func txTimestamp(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		logger.Error("Could not get transaction timestamp", err)
+		return time.Time{}, err
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+// getConfig Load an admin-managed JSON config record, reporting whether it exists
+func getConfig(stub shim.ChaincodeStubInterface, key string, v interface{}) (bool, error) {
+	configBytes, err := stub.GetState(key)
+	if err != nil {
+		logger.Error("Could not fetch config "+key+" from ledger", err)
+		return false, err
+	}
+	if configBytes == nil {
+		return false, nil
+	}
+	err = json.Unmarshal(configBytes, v)
+	if err != nil {
+		logger.Error("Could not unmarshal config "+key, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// putConfig Save an admin-managed JSON config record
+func putConfig(stub shim.ChaincodeStubInterface, key string, v interface{}) error {
+	configBytes, err := marshalState(v)
+	if err != nil {
+		logger.Error("Could not marshal config "+key, err)
+		return err
+	}
+	err = stub.PutState(key, configBytes)
+	if err != nil {
+		logger.Error("Could not save config "+key+" to ledger", err)
+		return err
+	}
+	return nil
+}
+
+// getLoanApplication Fetch and unmarshal a single loan application
+func getLoanApplication(stub shim.ChaincodeStubInterface, loanAppID string) (LoanApplication, error) {
+	var loanApplication LoanApplication
+	laBytes, err := stub.GetState(loanKey(loanAppID))
+	if err != nil {
+		logger.Error("Could not fetch loan application with id "+loanAppID+" from ledger", err)
+		return loanApplication, err
+	}
+	if laBytes == nil {
+		return loanApplication, errors.New("Loan application " + loanAppID + " does not exist")
+	}
+	err = json.Unmarshal(laBytes, &loanApplication)
+	if err != nil {
+		logger.Error("Could not unmarshal loan application "+loanAppID, err)
+		return loanApplication, err
+	}
+	return loanApplication, nil
+}
+
+// saveLoanApplication Stamp the modification date, recompute the risk score and save a loan application
+func saveLoanApplication(stub shim.ChaincodeStubInterface, loanApplication *LoanApplication) error {
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return err
+	}
+	loanApplication.LastModifiedDate = now.Format(time.RFC3339)
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return err
+	}
+	loanApplication.RiskScore = ComputeRiskScore(*loanApplication, params)
+	return putLoanApplication(stub, loanApplication, now)
+}
+
+// putLoanApplication Write an application exactly as given and record the version in its history
+func putLoanApplication(stub shim.ChaincodeStubInterface, loanApplication *LoanApplication, now time.Time) error {
+	laBytes, err := marshalState(loanApplication)
+	if err != nil {
+		logger.Error("Could not marshal loan application "+loanApplication.ID, err)
+		return err
+	}
+	err = stub.PutState(loanKey(loanApplication.ID), laBytes)
+	if err != nil {
+		logger.Error("Could not save loan application "+loanApplication.ID, err)
+		return err
+	}
+	return recordHistory(stub, loanApplication, now)
+}
+
+// getAllLoanApplications Scan the ledger and unmarshal every loan application found
+func getAllLoanApplications(stub shim.ChaincodeStubInterface) ([]LoanApplication, error) {
+	logger.Debug("Entering getAllLoanApplications")
+
+	iter, err := stub.RangeQueryState(loanKeyPrefix, loanKeyEnd)
+	if err != nil {
+		logger.Error("Could not start range query over loan applications", err)
+		return nil, err
+	}
+	defer iter.Close()
+
+	loanApplications := []LoanApplication{}
+	for iter.HasNext() {
+		key, laBytes, err := iter.Next()
+		if err != nil {
+			logger.Error("Could not read next loan application from range query", err)
+			return nil, err
+		}
+		var loanApplication LoanApplication
+		err = json.Unmarshal(laBytes, &loanApplication)
+		if err != nil {
+			logger.Error("Could not unmarshal loan application at key "+key, err)
+			return nil, err
+		}
+		loanApplications = append(loanApplications, loanApplication)
+	}
+	return loanApplications, nil
+}
+
+// getLoanApplicationsPage Read up to pageSize applications matching the filter after the
+// bookmark ID, returning the bookmark for the next page or empty when there are no more.
+// A nil filter matches every application.
+func getLoanApplicationsPage(stub shim.ChaincodeStubInterface, bookmark string, pageSize int, filter func(LoanApplication) bool) ([]LoanApplication, string, error) {
+	logger.Debug("Entering getLoanApplicationsPage")
+
+	// Appending the lowest rune gives the first key strictly after the bookmark
+	startKey := loanKeyPrefix
+	if bookmark != "" {
+		startKey = loanKey(bookmark) + string(rune(minUnicodeRuneValue))
+	}
+
+	iter, err := stub.RangeQueryState(startKey, loanKeyEnd)
+	if err != nil {
+		logger.Error("Could not start range query over loan applications", err)
+		return nil, "", err
+	}
+	defer iter.Close()
+
+	loanApplications := []LoanApplication{}
+	for iter.HasNext() {
+		key, laBytes, err := iter.Next()
+		if err != nil {
+			logger.Error("Could not read next loan application from range query", err)
+			return nil, "", err
+		}
+		var loanApplication LoanApplication
+		err = json.Unmarshal(laBytes, &loanApplication)
+		if err != nil {
+			logger.Error("Could not unmarshal loan application at key "+key, err)
+			return nil, "", err
+		}
+		if filter != nil && !filter(loanApplication) {
+			continue
+		}
+		if len(loanApplications) == pageSize {
+			// Another matching application exists beyond this page
+			return loanApplications, loanApplications[pageSize-1].ID, nil
+		}
+		loanApplications = append(loanApplications, loanApplication)
+	}
+	return loanApplications, "", nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestListAllLoanApplicationsExcludesOtherKeys(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+	seedApplication(t, stub, testApplication("LA-2"))
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	stub.mustInvoke(t, "SetLoanParameters", `{"maxDebtToIncome":0.4}`)
+	stub.state["loan"] = []byte(`{"id":"not-a-loan"}`)
+	stub.state["loanApplicationCounter"] = []byte("7")
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "ListAllLoanApplications")), "LA-1", "LA-2")
+}
+
+func TestMarshalStateIsCanonical(t *testing.T) {
+	type reordered struct {
+		Status string            `json:"status"`
+		ID     string            `json:"id"`
+		Rates  map[string]int    `json:"rates"`
+		Tags   map[string]string `json:"tags"`
+	}
+	first := map[string]interface{}{
+		"id":     "LA-1",
+		"status": statusSubmitted,
+		"rates":  map[string]int{"A": 500, "B": 750, "C": 1000},
+		"tags":   map[string]string{"vip": "yes", "branch": "north"},
+	}
+	second := reordered{
+		Status: statusSubmitted,
+		ID:     "LA-1",
+		Rates:  map[string]int{"C": 1000, "A": 500, "B": 750},
+		Tags:   map[string]string{"branch": "north", "vip": "yes"},
+	}
+
+	expected, err := marshalState(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := marshalState(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("expected byte-identical state, got %s and %s", expected, actual)
+	}
+	want := `{"id":"LA-1","rates":{"A":500,"B":750,"C":1000},"status":"Submitted","tags":{"branch":"north","vip":"yes"}}`
+	if string(actual) != want {
+		t.Fatalf("expected keys sorted at every level, got %s", actual)
+	}
+}
+
+func TestPutStateDeterministicAcrossEndorsers(t *testing.T) {
+	endorse := func(rates string) *mockStub {
+		stub := newMockStub()
+		stub.mustInvoke(t, "SetCreditTierRates", rates)
+		createApplication(t, stub, testApplication("LA-1"))
+		stub.mustInvoke(t, "AddTag", "LA-1", "vip")
+		return stub
+	}
+	first := endorse(`{"A":5,"B":7.5,"C":10}`)
+	second := endorse(`{"C":10,"A":5,"B":7.5}`)
+
+	if len(first.state) != len(second.state) {
+		t.Fatalf("expected the same keys written, got %d and %d", len(first.state), len(second.state))
+	}
+	for key, value := range first.state {
+		if !bytes.Equal(value, second.state[key]) {
+			t.Fatalf("expected identical state at %q, got %s and %s", key, value, second.state[key])
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// ensureUnlocked Refuse to mutate an application frozen by LockLoanApplication
+func ensureUnlocked(loanApplication LoanApplication) error {
+	if loanApplication.Locked {
+		return errors.New("Loan application " + loanApplication.ID + " is locked by " + loanApplication.LockedBy + " and cannot be modified")
+	}
+	return nil
+}
+
+// LockLoanApplication Freeze application args[0] with optional reason args[1]
+func LockLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering LockLoanApplication")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var loanAppID = args[0]
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+
+	username, _ := GetCertAttribute(stub, "username")
+	loanApplication.Locked = true
+	loanApplication.LockedBy = username
+	if len(args) > 1 {
+		loanApplication.LockReason = args[1]
+	}
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "loanApplicationLocked", loanAppID+" locked by "+username, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully locked loan application")
+	return nil, nil
+}
+
+// UnlockLoanApplication Release the freeze on application args[0]
+func UnlockLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering UnlockLoanApplication")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var loanAppID = args[0]
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if !loanApplication.Locked {
+		return nil, errors.New("Loan application " + loanAppID + " is not locked")
+	}
+
+	username, _ := GetCertAttribute(stub, "username")
+	loanApplication.Locked = false
+	loanApplication.LockedBy = ""
+	loanApplication.LockReason = ""
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "loanApplicationUnlocked", loanAppID+" unlocked by "+username, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully unlocked loan application")
+	return nil, nil
+}
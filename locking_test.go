@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMutationsFailWhileLocked(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	seedApplication(t, stub, testApplication("LA-1"))
+	stub.mustInvoke(t, "LockLoanApplication", "LA-1", "fraud investigation")
+	before := string(stub.state[loanKey("LA-1")])
+
+	mutations := [][]string{
+		{"UpdateLoanApplication", "LA-1", statusUnderReview},
+		{"PatchLoanApplication", "LA-1", `{"termMonths":240}`},
+		{"AssignReviewer", "LA-1", "rev1"},
+		{"TransferApplicationBuyer", "LA-1", "BUYER-2"},
+		{"AddNote", "LA-1", "note"},
+		{"AddTag", "LA-1", "vip"},
+		{"RecordConsent", "LA-1", "pii", "true"},
+		{"LockLoanApplication", "LA-1"},
+	}
+	for _, mutation := range mutations {
+		_, err := stub.invoke(mutation[0], mutation[1:]...)
+		if err == nil || !strings.Contains(err.Error(), "is locked by admin") {
+			t.Errorf("expected %s to fail while locked, got %v", mutation[0], err)
+		}
+	}
+	if string(stub.state[loanKey("LA-1")]) != before {
+		t.Fatal("expected the locked application to be unchanged")
+	}
+
+	stub.mustInvoke(t, "UnlockLoanApplication", "LA-1")
+	stub.mustInvoke(t, "UpdateLoanApplication", "LA-1", statusUnderReview)
+	if loanApplication := storedApplication(t, stub, "LA-1"); loanApplication.Locked || loanApplication.Status != statusUnderReview {
+		t.Fatalf("expected the unlocked application to accept changes, got %+v", loanApplication)
+	}
+}
+
+func TestLockRequiresAdmin(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	_, err := stub.as("rev1", roleReviewer).invoke("LockLoanApplication", "LA-1")
+	if _, ok := err.(*PermissionError); !ok {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 )
@@ -12,70 +15,762 @@ var logger = shim.NewLogger("mylogger")
 
 // PersonalInfo schema
 type PersonalInfo struct {
-	Firstname string `json:"firstname"`
-	Lastname  string `json:"lastname"`
-	DOB       string `json:"DOB"`
-	Email     string `json:"email"`
-	Mobile    string `json:"mobile"`
+	Firstname string `json:"firstname" validate:"required"`
+	Lastname  string `json:"lastname" validate:"required"`
+	DOB       string `json:"DOB" validate:"required,date"`
+	Email     string `json:"email" validate:"required,email"`
+	Mobile    string `json:"mobile" validate:"required,phone"`
 }
 
 // FinancialInfo schema
 type FinancialInfo struct {
-	MonthlySalary      int `json:"monthlySalary"`
-	MonthlyRent        int `json:"monthlyRent"`
-	OtherExpenditure   int `json:"otherExpenditure"`
-	MonthlyLoanPayment int `json:"monthlyLoanPayment"`
+	MonthlySalary      int64 `json:"monthlySalary" validate:"min=0"`
+	MonthlyRent        int64 `json:"monthlyRent" validate:"min=0"`
+	OtherExpenditure   int64 `json:"otherExpenditure" validate:"min=0"`
+	MonthlyLoanPayment int64 `json:"monthlyLoanPayment" validate:"min=0"`
+}
+
+// Document schema
+type Document struct {
+	Type      string `json:"type"`
+	Reference string `json:"reference"`
+}
+
+// StatusChange schema
+type StatusChange struct {
+	Status     string `json:"status"`
+	Date       string `json:"date"`
+	ChangedBy  string `json:"changedBy"`
+	ReviewerID string `json:"ReviewerID"`
+	Note       string `json:"note"`
+	MergedFrom string `json:"mergedFrom,omitempty"`
+}
+
+// Tranche schema
+type Tranche struct {
+	Amount int64  `json:"amount"`
+	Date   string `json:"date"`
+	TxID   string `json:"txId"`
+}
+
+// DisbursementInfo schema
+type DisbursementInfo struct {
+	DisbursedAmount int64     `json:"disbursedAmount"`
+	Tranches        []Tranche `json:"tranches"`
 }
 
 // LoanApplication schema
 type LoanApplication struct {
-	ID               string        `json:"id"`
-	PropertyID       string        `json:"PropertyID"`
-	LandID           string        `json:"LandID"`
-	PermitID         string        `json:"PermitID"`
-	BuyerID          string        `json:"BuyerID"`
-	SalesContractID  string        `json:"SalesContractID"`
-	PersonalInfo     PersonalInfo  `json:"personalInfo"`
-	FinancialInfo    FinancialInfo `json:"financialInfo"`
-	Status           string        `json:"status"`
-	RequestedAmount  int           `json:"requestedAmount"`
-	FairMarketValue  int           `json:"fairMarketValue"`
-	ApprovedAmount   int           `json:"approvedAmount"`
-	ReviewerID       string        `json:"ReviewerID"`
-	LastModifiedDate string        `json:"lastModifiedDate"`
+	ID                      string              `json:"id"`
+	PropertyID              string              `json:"PropertyID"`
+	LandID                  string              `json:"LandID"`
+	PermitID                string              `json:"PermitID"`
+	BuyerID                 string              `json:"BuyerID"`
+	SalesContractID         string              `json:"SalesContractID"`
+	ProductType             string              `json:"productType"`
+	PersonalInfo            PersonalInfo        `json:"personalInfo"`
+	PIIPurged               bool                `json:"piiPurged"`
+	Consents                []Consent           `json:"consents"`
+	FinancialInfo           FinancialInfo       `json:"financialInfo"`
+	Status                  string              `json:"status"`
+	MergedInto              string              `json:"mergedInto"`
+	RequestedAmount         int64               `json:"requestedAmount"`
+	TermMonths              int                 `json:"termMonths"`
+	Currency                string              `json:"currency" validate:"currency"`
+	FairMarketValue         int64               `json:"fairMarketValue"`
+	FairMarketValueCurrency string              `json:"fairMarketValueCurrency" validate:"currency"`
+	AppraisalDate           string              `json:"appraisalDate" validate:"date"`
+	ApprovedAmount          int64               `json:"approvedAmount"`
+	ApprovalExpiryDate      string              `json:"approvalExpiryDate"`
+	DisbursementInfo        DisbursementInfo    `json:"disbursementInfo"`
+	ReviewerID              string              `json:"ReviewerID"`
+	Escalated               bool                `json:"escalated"`
+	EscalationReason        string              `json:"escalationReason"`
+	DecisionCode            string              `json:"decisionCode"`
+	Conditions              []ApprovalCondition `json:"conditions"`
+	RejectionReason         string              `json:"rejectionReason"`
+	CreditTier              string              `json:"creditTier"`
+	InterestRateBps         int                 `json:"interestRateBps"`
+	RiskScore               int                 `json:"riskScore"`
+	Documents               []Document          `json:"documents"`
+	Notes                   []Note              `json:"notes"`
+	Tags                    []string            `json:"tags"`
+	StatusHistory           []StatusChange      `json:"statusHistory"`
+	RemindersSent           int                 `json:"remindersSent"`
+	ReminderDate            string              `json:"reminderDate"`
+	Locked                  bool                `json:"locked"`
+	LockedBy                string              `json:"lockedBy"`
+	LockReason              string              `json:"lockReason"`
+	CreatedBy               string              `json:"createdBy"`
+	CreatedByRole           string              `json:"createdByRole"`
+	CreatedDate             string              `json:"createdDate"`
+	LastModifiedDate        string              `json:"lastModifiedDate"`
+}
+
+// Caller roles
+const (
+	roleAdmin          = "Bank_Home_Loan_Admin"
+	roleReviewer       = "Bank_Home_Loan_Reviewer"
+	roleSeniorReviewer = "Bank_Home_Loan_Senior_Reviewer"
+	roleApplicant      = "Applicant"
+	roleAuditor        = "Auditor"
+)
+
+// Loan application statuses
+const (
+	statusSubmitted             = "Submitted"
+	statusUnderReview           = "UnderReview"
+	statusApproved              = "Approved"
+	statusPendingSecondApproval = "PendingSecondApproval"
+	statusConditionallyApproved = "ConditionallyApproved"
+	statusRejected              = "Rejected"
+	statusDisbursed             = "Disbursed"
+	statusArchived              = "Archived"
+	statusExpired               = "Expired"
+	statusMerged                = "Merged"
+	statusApprovalExpired       = "ApprovalExpired"
+)
+
+// validStatuses Every status a loan application may hold
+var validStatuses = map[string]bool{
+	statusSubmitted:             true,
+	statusUnderReview:           true,
+	statusPendingSecondApproval: true,
+	statusConditionallyApproved: true,
+	statusApproved:              true,
+	statusRejected:              true,
+	statusDisbursed:             true,
+	statusArchived:              true,
+	statusExpired:               true,
+	statusMerged:                true,
+	statusApprovalExpired:       true,
+}
+
+// statusProgress How far through the workflow each status is, as a percentage
+var statusProgress = map[string]int{
+	statusSubmitted:             10,
+	statusUnderReview:           40,
+	statusPendingSecondApproval: 60,
+	statusConditionallyApproved: 70,
+	statusApproved:              80,
+	statusDisbursed:             100,
+	statusRejected:              100,
+	statusArchived:              100,
+	statusExpired:               100,
+	statusMerged:                100,
+	statusApprovalExpired:       100,
+}
+
+// terminalStatuses Statuses an application never leaves except to be archived or purged
+var terminalStatuses = map[string]bool{
+	statusDisbursed:       true,
+	statusRejected:        true,
+	statusArchived:        true,
+	statusExpired:         true,
+	statusMerged:          true,
+	statusApprovalExpired: true,
+}
+
+// preDisbursementStatuses Statuses of applications whose funds have not yet been released
+var preDisbursementStatuses = map[string]bool{
+	statusSubmitted:             true,
+	statusUnderReview:           true,
+	statusPendingSecondApproval: true,
+	statusConditionallyApproved: true,
+	statusApproved:              true,
 }
 
 type customEvent struct {
-	Type       string `json:"type"`
-	Decription string `json:"description"`
+	SchemaVersion int         `json:"schemaVersion"`
+	Type          string      `json:"type"`
+	ID            string      `json:"id,omitempty"`
+	Decription    string      `json:"description,omitempty"`
+	Payload       interface{} `json:"payload,omitempty"`
+	Truncated     bool        `json:"truncated,omitempty"`
 }
 
 // Sample chain code API
 type SampleChainCode struct{}
 
-// Stubbed init method
+// Init optionally enables the cert attribute fallback with args[0], for networks
+// where no caller holds the attributes needed to enable it later
 func (t *SampleChainCode) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	if len(args) > 0 && args[0] != "" {
+		return SetCertAttributeFallback(stub, args)
+	}
 	return nil, nil
 }
 
 // Query for existing
 func (t *SampleChainCode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	if err := ensureFunctionEnabled(stub, function); err != nil {
+		return nil, err
+	}
 	if function == "GetLoanApplication" {
 		return GetLoanApplication(stub, args)
 	}
+	if function == "HealthCheck" {
+		return HealthCheck(stub, args)
+	}
+	if function == "ListAllLoanApplications" {
+		return ListAllLoanApplications(stub, args)
+	}
+	if function == "QueryByStatuses" {
+		return QueryByStatuses(stub, args)
+	}
+	if function == "GetLoanApplicationSummary" {
+		return GetLoanApplicationSummary(stub, args)
+	}
+	if function == "GetLoanApplicationsSummaryPage" {
+		return GetLoanApplicationsSummaryPage(stub, args)
+	}
+	if function == "GetLoanApplicationsRequiringReappraisal" {
+		return GetLoanApplicationsRequiringReappraisal(stub, args)
+	}
+	if function == "GetLoanApplicationDiff" {
+		return GetLoanApplicationDiff(stub, args)
+	}
+	if function == "GetLoanApplicationsByAgeRange" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationsByAgeRange(stub, args)
+	}
+	if function == "GetApplicationsExceedingDTI" {
+		return GetApplicationsExceedingDTI(stub, args)
+	}
+	if function == "GetLoanApplicationsWithoutReviewer" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationsWithoutReviewer(stub, args)
+	}
+	if function == "GetApplicationProgress" {
+		return GetApplicationProgress(stub, args)
+	}
+	if function == "GetLoanApplicationNotes" {
+		return GetLoanApplicationNotes(stub, args)
+	}
+	if function == "GetLoanApplicationsByLandID" {
+		return GetLoanApplicationsByLandID(stub, args)
+	}
+	if function == "ExportLoanApplications" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return ExportLoanApplications(stub, args)
+	}
+	if function == "GetLoanApplicationsWithPendingActions" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationsWithPendingActions(stub, args)
+	}
+	if function == "ProjectField" {
+		return ProjectField(stub, args)
+	}
+	if function == "GetLoanApplicationCountByReviewer" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationCountByReviewer(stub, args)
+	}
+	if function == "GetCreditTierRates" {
+		return GetCreditTierRates(stub, args)
+	}
+	if function == "GetLoanParameters" {
+		return GetLoanParameters(stub, args)
+	}
+	if function == "GetDecisionCodes" {
+		return GetDecisionCodes(stub, args)
+	}
+	if function == "GetReviewerRoster" {
+		return GetReviewerRoster(stub, args)
+	}
+	if function == "GetLoanApplicationsPendingDisbursement" {
+		return GetLoanApplicationsPendingDisbursement(stub, args)
+	}
+	if function == "GetLoanApplicationsByCurrency" {
+		return GetLoanApplicationsByCurrency(stub, args)
+	}
+	if function == "GetTotalExposureByProperty" {
+		return GetTotalExposureByProperty(stub, args)
+	}
+	if function == "GetProductSchemas" {
+		return GetProductSchemas(stub, args)
+	}
+	if function == "GetLoanApplicationsByStatusPage" {
+		return GetLoanApplicationsByStatusPage(stub, args)
+	}
+	if function == "GetApplicationsByRiskScoreRange" {
+		return GetApplicationsByRiskScoreRange(stub, args)
+	}
+	if function == "GetLoanApplicationsCreatedByRole" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationsCreatedByRole(stub, args)
+	}
+	if function == "GetLoanApplicationRaw" {
+		if err := checkRole(stub, function, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationRaw(stub, args)
+	}
+	if function == "GetReviewQueueByRisk" {
+		if err := checkRole(stub, function, roleReviewer); err != nil {
+			return nil, err
+		}
+		return GetReviewQueueByRisk(stub, args)
+	}
+	if function == "GetApplicationsMissingReferences" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetApplicationsMissingReferences(stub, args)
+	}
+	if function == "CalculateMonthlyPayment" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer, roleApplicant); err != nil {
+			return nil, err
+		}
+		return CalculateMonthlyPayment(stub, args)
+	}
+	if function == "GetLoanApplicationsByDecisionCode" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationsByDecisionCode(stub, args)
+	}
+	if function == "GetApplicationsSubmittedPerDay" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetApplicationsSubmittedPerDay(stub, args)
+	}
+	if function == "GetApprovalTurnaroundMetrics" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetApprovalTurnaroundMetrics(stub, args)
+	}
+	if function == "GetReviewerDecisionStats" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetReviewerDecisionStats(stub, args)
+	}
+	if function == "GetLoanApplicationsByTag" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationsByTag(stub, args)
+	}
+	if function == "GetLoanApplicationsByFairMarketValueRange" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationsByFairMarketValueRange(stub, args)
+	}
+	if function == "GetLoanApplicationChecksum" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetLoanApplicationChecksum(stub, args)
+	}
+	if function == "SearchLoanApplications" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return SearchLoanApplications(stub, args)
+	}
+	if function == "GetHandlerMetrics" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return GetHandlerMetrics(stub, args)
+	}
+	if function == "RevalidateAll" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return RevalidateAll(stub, args)
+	}
+	if function == "GetTerminalApplications" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetTerminalApplications(stub, args)
+	}
+	if function == "SelfCheck" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return SelfCheck(stub, args)
+	}
+	if function == "GetLoanApplicationJSONSchema" {
+		return GetLoanApplicationJSONSchema(stub, args)
+	}
+	if function == "QueryLoanApplications" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return QueryLoanApplications(stub, args)
+	}
+	if function == "GetAmortizationSchedule" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer, roleApplicant); err != nil {
+			return nil, err
+		}
+		return GetAmortizationSchedule(stub, args)
+	}
+	if function == "GetStaleAssignments" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return GetStaleAssignments(stub, args)
+	}
+	if function == "GetMaskedFields" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetMaskedFields(stub, args)
+	}
+	if function == "GetApplicationsByCreatedByUser" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetApplicationsByCreatedByUser(stub, args)
+	}
+	if function == "GetApplicationsByTermRange" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return GetApplicationsByTermRange(stub, args)
+	}
+	if function == "GetBusinessCalendar" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetBusinessCalendar(stub, args)
+	}
+	if function == "GetApplicationsByInterestRateRange" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return GetApplicationsByInterestRateRange(stub, args)
+	}
+	if function == "GetApplicationStateCountsOverTime" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetApplicationStateCountsOverTime(stub, args)
+	}
+	if function == "GetEnabledFunctions" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return GetEnabledFunctions(stub, args)
+	}
+	if function == "GetApplicationsByBuyers" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return GetApplicationsByBuyers(stub, args)
+	}
+	if function == "GetApplicationAuditBundle" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer, roleAuditor, roleApplicant); err != nil {
+			return nil, err
+		}
+		return GetApplicationAuditBundle(stub, args)
+	}
+	if function == "GetApplicationsWithExpiredConsent" {
+		if err := checkRole(stub, function, roleAdmin, roleAuditor); err != nil {
+			return nil, err
+		}
+		return GetApplicationsWithExpiredConsent(stub, args)
+	}
 	return nil, nil
 }
 
-// Invoke creation of new application
+// Invoke creation of new application, counting each successful call per function
 func (t *SampleChainCode) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	err := ensureFunctionEnabled(stub, function)
+	if err != nil {
+		return nil, err
+	}
+	result, err := t.dispatchInvoke(stub, function, args)
+	if err != nil {
+		// A failed transaction discards its writes, so failures are logged rather than counted
+		logger.Error("Invoke of " + function + " failed: " + err.Error())
+		return nil, err
+	}
+	err = recordHandlerCall(stub, function)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// dispatchInvoke Route an invocation to its handler after checking the caller's role
+func (t *SampleChainCode) dispatchInvoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	if function == "CreateLoanApplication" {
-		username, _ := GetCertAttribute(stub, "username")
-		role, _ := GetCertAttribute(stub, "role")
-		if role == "Bank_Home_Loan_Admin" {
-			return CreateLoanApplication(stub, args)
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return CreateLoanApplication(stub, args)
+	}
+	if function == "SetReviewerRoster" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetReviewerRoster(stub, args)
+	}
+	if function == "AssignReviewer" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return AssignReviewer(stub, args)
+	}
+	if function == "SetCreditTierRates" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetCreditTierRates(stub, args)
+	}
+	if function == "SetLoanParameters" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetLoanParameters(stub, args)
+	}
+	if function == "ApproveLoanApplication" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return ApproveLoanApplication(stub, args)
+	}
+	if function == "ArchiveLoanApplication" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return ArchiveLoanApplication(stub, args)
+	}
+	if function == "PurgeArchivedApplications" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return PurgeArchivedApplications(stub, args)
+	}
+	if function == "UpdateLoanApplication" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return UpdateLoanApplication(stub, args)
+	}
+	if function == "PatchLoanApplication" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return PatchLoanApplication(stub, args)
+	}
+	if function == "RejectLoanApplication" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return RejectLoanApplication(stub, args)
+	}
+	if function == "SetDecisionCodes" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetDecisionCodes(stub, args)
+	}
+	if function == "RebuildIndexes" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return RebuildIndexes(stub, args)
+	}
+	if function == "TransferApplicationBuyer" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return TransferApplicationBuyer(stub, args)
+	}
+	if function == "SetEventsEnabled" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetEventsEnabled(stub, args)
+	}
+	if function == "AddNote" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return AddNote(stub, args)
+	}
+	if function == "AutoAssignReviewer" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return AutoAssignReviewer(stub, args)
+	}
+	if function == "ExpireStaleApplications" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return ExpireStaleApplications(stub, args)
+	}
+	if function == "SetCertAttributeFallback" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetCertAttributeFallback(stub, args)
+	}
+	if function == "DisburseLoanApplication" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return DisburseLoanApplication(stub, args)
+	}
+	if function == "SetApproverKeys" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetApproverKeys(stub, args)
+	}
+	if function == "SubmitSignedApproval" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return SubmitSignedApproval(stub, args)
+	}
+	if function == "SetProductSchemas" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetProductSchemas(stub, args)
+	}
+	if function == "LockLoanApplication" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return LockLoanApplication(stub, args)
+	}
+	if function == "UnlockLoanApplication" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return UnlockLoanApplication(stub, args)
+	}
+	if function == "ReassignAllForReviewer" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return ReassignAllForReviewer(stub, args)
+	}
+	if function == "BatchApprove" {
+		if err := checkRole(stub, function, roleReviewer); err != nil {
+			return nil, err
+		}
+		return BatchApprove(stub, args)
+	}
+	if function == "PurgePII" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return PurgePII(stub, args)
+	}
+	if function == "AddTag" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return AddTag(stub, args)
+	}
+	if function == "RemoveTag" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return RemoveTag(stub, args)
+	}
+	if function == "ApproveWithConditions" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return ApproveWithConditions(stub, args)
+	}
+	if function == "SatisfyCondition" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer); err != nil {
+			return nil, err
+		}
+		return SatisfyCondition(stub, args)
+	}
+	if function == "CloneLoanApplication" {
+		if err := checkRole(stub, function, roleAdmin, roleApplicant); err != nil {
+			return nil, err
+		}
+		return CloneLoanApplication(stub, args)
+	}
+	if function == "EscalateApplication" {
+		if err := checkRole(stub, function, roleAdmin, roleSeniorReviewer); err != nil {
+			return nil, err
+		}
+		return EscalateApplication(stub, args)
+	}
+	if function == "SetMaskedFields" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetMaskedFields(stub, args)
+	}
+	if function == "BackfillCreatedBy" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return BackfillCreatedBy(stub, args)
+	}
+	if function == "SetBusinessCalendar" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetBusinessCalendar(stub, args)
+	}
+	if function == "RecomputeAllRiskScores" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return RecomputeAllRiskScores(stub, args)
+	}
+	if function == "SetEnabledFunctions" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return SetEnabledFunctions(stub, args)
+	}
+	if function == "MergeApplications" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return MergeApplications(stub, args)
+	}
+	if function == "ExpireApprovals" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return ExpireApprovals(stub, args)
+	}
+	if function == "RecordConsent" {
+		if err := checkRole(stub, function, roleAdmin, roleReviewer, roleApplicant); err != nil {
+			return nil, err
 		}
-		return nil, errors.Name(username + " with role " + role + " does not have correct permissions")
+		return RecordConsent(stub, args)
 	}
+	if function == "MigrateInterestRates" {
+		if err := checkRole(stub, function, roleAdmin); err != nil {
+			return nil, err
+		}
+		return MigrateInterestRates(stub, args)
+	}
+	return nil, errors.New("Unknown function " + function)
 }
 
 func main() {
@@ -94,7 +789,7 @@ func main() {
 	}
 }
 
-// CreateLoanApplication Create loan application from args
+// CreateLoanApplication Create loan application from args, generating an LN- ID when args[0] is empty
 func CreateLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	logger.Debug("Entering CreateLoanApplication")
 
@@ -106,20 +801,117 @@ func CreateLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]b
 	var loanAppID = args[0]
 	var loanAppInput = args[1]
 
-	err := stub.PutState(loanAppID, []byte(loanAppInput))
+	var loanApplication LoanApplication
+	err := json.Unmarshal([]byte(loanAppInput), &loanApplication)
+	if err != nil {
+		logger.Error("Could not unmarshal loan application input", err)
+		return nil, errors.New("Invalid loan application: " + err.Error())
+	}
+	violations, err := validateLoanApplication(stub, loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		logger.Error("Loan application failed validation")
+		return nil, errors.New("Invalid loan application: " + strings.Join(violations, "; "))
+	}
+
+	// Writes are staged and only flushed once every step has succeeded, so a failed create
+	// does not consume a generated ID
+	staged := newStagedStub(stub)
+	generatedID := loanAppID == ""
+	if generatedID {
+		loanAppID, err = nextLoanApplicationID(staged)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Overwriting would discard the existing record and strand its index entries
+		existing, err := stub.GetState(loanKey(loanAppID))
+		if err != nil {
+			logger.Error("Could not fetch loan application "+loanAppID+" from ledger", err)
+			return nil, err
+		}
+		if existing != nil {
+			logger.Error("Loan application " + loanAppID + " already exists")
+			return nil, errors.New("Loan application " + loanAppID + " already exists")
+		}
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	username, _ := GetCertAttribute(stub, "username")
+	role, _ := GetCertAttribute(stub, "role")
+
+	loanApplication.ID = loanAppID
+	loanApplication.CreatedBy = username
+	loanApplication.CreatedByRole = role
+	loanApplication.CreatedDate = now.Format(time.RFC3339)
+	// Interest rate is derived from the credit tier on approval, never client supplied
+	loanApplication.InterestRateBps = 0
+	// Tags are managed with AddTag and RemoveTag
+	loanApplication.Tags = nil
+	// Consents are captured with RecordConsent so who recorded them is known
+	loanApplication.Consents = nil
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	err = checkPropertyCapacity(stub, loanApplication.PropertyID, params)
+	if err != nil {
+		return nil, err
+	}
+	duplicateID, err := findDuplicateApplication(stub, loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	if duplicateID != "" && params.RejectDuplicates {
+		logger.Error("Loan application " + loanAppID + " duplicates " + duplicateID)
+		return nil, errors.New("Loan application appears to duplicate existing application " + duplicateID)
+	}
+
+	err = routeNewApplication(stub, &loanApplication, params, now)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveLoanApplication(staged, &loanApplication)
 	if err != nil {
 		logger.Error("Could not save loan application to ledger", err)
 		return nil, err
 	}
+	err = addLoanIndexes(staged, loanApplication)
+	if err != nil {
+		return nil, err
+	}
 
-	var customEvent = "{eventType: 'loanApplicationCreation', description: '" + loanAppID + " Successfully created'}"
-	err = stub.SetEvent("evtSender", []byte(customEvent))
+	err = sendEvent(staged, "loanApplicationCreation", loanAppID+" Successfully created", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = staged.flush()
 	if err != nil {
 		return nil, err
 	}
 
 	logger.Info("Successfully saved loan application")
-	return nil, nil
+	response := createResponse{}
+	if generatedID {
+		response.ID = loanAppID
+	}
+	if duplicateID != "" {
+		logger.Warning("Loan application " + loanAppID + " may duplicate " + duplicateID)
+		response.Warning = "possible duplicate"
+		response.ExistingID = duplicateID
+	}
+	if response == (createResponse{}) {
+		return nil, nil
+	}
+	return json.Marshal(response)
 }
 
 // GetLoanApplication Get existing application by ID
@@ -132,11 +924,59 @@ func GetLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte
 	}
 
 	var loanAppId = args[0]
-	bytes, err := stub.GetState(loanAppId)
+	bytes, err := stub.GetState(loanKey(loanAppId))
 	if err != nil {
 		logger.Error("Could not fetch loan application with id "+loanAppId+" from ledger", err)
 		return nil, err
 	}
+	if bytes == nil {
+		return bytes, nil
+	}
+
+	var loanApplication LoanApplication
+	err = json.Unmarshal(bytes, &loanApplication)
+	if err != nil {
+		logger.Error("Could not unmarshal loan application "+loanAppId, err)
+		return nil, err
+	}
+	// Staff still see the application, but not personal information the applicant has not consented to
+	view, err := loanApplicationViewer(stub)
+	if err != nil {
+		return nil, err
+	}
+	return view(loanApplication)
+}
+
+// GetLoanApplicationRaw Get existing application by ID without redaction, for auditors
+func GetLoanApplicationRaw(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationRaw")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of arguments")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var loanAppID = args[0]
+	username, _ := GetCertAttribute(stub, "username")
+	logger.Info("Auditor " + username + " accessed unredacted loan application " + loanAppID + " in transaction " + stub.GetTxID())
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	consented, err := piiConsentChecker(stub)
+	if err != nil {
+		return nil, err
+	}
+	if !consented(loanApplication) {
+		return nil, errors.New("Loan application " + loanAppID + " is missing required consent to expose personal information")
+	}
+
+	bytes, err := stub.GetState(loanKey(loanAppID))
+	if err != nil {
+		logger.Error("Could not fetch loan application with id "+loanAppID+" from ledger", err)
+		return nil, err
+	}
 	return bytes, nil
 }
 
@@ -152,30 +992,37 @@ func UpdateLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]b
 	var loanAppID = args[0]
 	var status = args[1]
 
-	laBytes, err := stub.GetState(loanAppId)
+	loanApplication, err := getLoanApplication(stub, loanAppID)
 	if err != nil {
 		logger.Error("Could not fetch loan application from ledger", err)
 		return nil, err
 	}
-	var loanApplication loanApplication
-	err = json.Unmarshal(laBytes, &loanApplication)
-	loanApplication.Status = status
-
-	laBytes, err = json.Marshal(&loanApplication)
-
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	if !validStatuses[status] {
+		logger.Error("Invalid status " + status)
+		return nil, errors.New("Unknown loan application status '" + status + "'")
+	}
+	err = setStatus(stub, &loanApplication, status, "status updated")
 	if err != nil {
-		logger.Error("Could not marshal loan application post update", err)
 		return nil, err
 	}
 
-	err = stub.PutState(loanAppID, laBytes)
+	// Writes are staged and only flushed once every step has succeeded
+	staged := newStagedStub(stub)
+	err = saveLoanApplication(staged, &loanApplication)
 	if err != nil {
 		logger.Error("Could not save loan application post update", err)
 		return nil, err
 	}
 
-	var customEvent = "{eventType: 'loanApplicationUpdate', description: '" + loanAppID + " Successfully updated'}"
-	err = stub.SetEvent("evtSender", []byte(customEvent))
+	err = sendEvent(staged, "loanApplicationUpdate", loanAppID+" Successfully updated", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = staged.flush()
 	if err != nil {
 		return nil, err
 	}
@@ -184,13 +1031,151 @@ func UpdateLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]b
 	return nil, nil
 }
 
-// GetCertAttribute Get particular attribute from JSON
+// GetCertAttribute Get particular attribute from JSON, falling back to the caller
+// certificate subject when enabled and the attribute is unavailable
 func GetCertAttribute(stub shim.ChaincodeStubInterface, attributeName string) (string, error) {
 	logger.Debug("Entering GetCertAttribute")
 	attr, err := stub.ReadCertAttribute(attributeName)
-	if err != nil {
+	if err != nil || len(attr) == 0 {
+		if certAttributeFallbackEnabled(stub) {
+			if derived, derr := creatorAttribute(stub, attributeName); derr == nil {
+				return derived, nil
+			}
+		}
+		if err == nil {
+			err = errors.New("attribute is empty")
+		}
 		return "", errors.New("Couldn't get attribute " + attributeName + ". Error: " + err.Error())
 	}
 	attrString := string(attr)
 	return attrString, nil
 }
+
+// PermissionError Returned when the caller's role is not allowed to run a function
+type PermissionError struct {
+	Username string
+	Role     string
+	Function string
+}
+
+func (e *PermissionError) Error() string {
+	return e.Username + " with role " + e.Role + " does not have correct permissions for " + e.Function
+}
+
+// checkRole Ensure the caller holds one of the given roles to run function
+func checkRole(stub shim.ChaincodeStubInterface, function string, roles ...string) error {
+	username, _ := GetCertAttribute(stub, "username")
+	role, _ := GetCertAttribute(stub, "role")
+	for _, r := range roles {
+		if role == r {
+			return nil
+		}
+	}
+	return &PermissionError{Username: username, Role: role, Function: function}
+}
+
+// ownsApplication Report whether the caller is the buyer an application was created for. Applications are
+// created by bank staff, so an applicant is known by the buyer ID their enrollment username carries.
+func ownsApplication(stub shim.ChaincodeStubInterface, loanApplication LoanApplication) bool {
+	username, _ := GetCertAttribute(stub, "username")
+	return username != "" && username == loanApplication.BuyerID
+}
+
+// patchableFields Applicant data a merge patch may change; workflow state, decisions, tags, consents and
+// audit fields only move through their own handlers
+var patchableFields = map[string]bool{
+	"PropertyID":              true,
+	"LandID":                  true,
+	"PermitID":                true,
+	"SalesContractID":         true,
+	"productType":             true,
+	"personalInfo":            true,
+	"financialInfo":           true,
+	"requestedAmount":         true,
+	"termMonths":              true,
+	"currency":                true,
+	"fairMarketValue":         true,
+	"fairMarketValueCurrency": true,
+	"appraisalDate":           true,
+	"creditTier":              true,
+	"documents":               true,
+}
+
+// PatchLoanApplication Apply JSON merge patch args[1] of applicant data fields to existing application args[0]
+func PatchLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering PatchLoanApplication")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID and patch")
+	}
+
+	var loanAppID = args[0]
+	var patch = args[1]
+
+	original, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(original); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal([]byte(patch), &fields)
+	if err != nil {
+		logger.Error("Could not unmarshal patch for loan application "+loanAppID, err)
+		return nil, errors.New("Invalid loan application patch: " + err.Error())
+	}
+	// Keys must match exactly, otherwise case-insensitive unmarshalling would reach fields outside the list
+	var rejected []string
+	for field := range fields {
+		if !patchableFields[field] {
+			rejected = append(rejected, field)
+		}
+	}
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		logger.Error("Patch attempted to change protected fields of " + loanAppID)
+		return nil, errors.New("Fields " + strings.Join(rejected, ", ") + " cannot be patched")
+	}
+
+	// Unmarshalling into a copy of the stored record leaves fields absent from the patch untouched
+	patched := original
+	err = json.Unmarshal([]byte(patch), &patched)
+	if err != nil {
+		logger.Error("Could not apply patch to loan application "+loanAppID, err)
+		return nil, errors.New("Invalid loan application patch: " + err.Error())
+	}
+
+	violations, err := validateLoanApplication(stub, patched)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		logger.Error("Patched loan application failed validation")
+		return nil, errors.New("Invalid loan application: " + strings.Join(violations, "; "))
+	}
+
+	err = saveLoanApplication(stub, &patched)
+	if err != nil {
+		return nil, err
+	}
+	// Indexed fields may have been patched
+	err = removeLoanIndexes(stub, original)
+	if err != nil {
+		return nil, err
+	}
+	err = addLoanIndexes(stub, patched)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "loanApplicationUpdate", loanAppID+" Successfully patched", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully patched loan application")
+	return nil, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPatchLoanApplicationMultipleFields(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	stub.mustInvoke(t, "PatchLoanApplication", "LA-1", `{"requestedAmount":250000,"LandID":"LAND-9","personalInfo":{"mobile":"+61411111111"}}`)
+
+	loanApplication := storedApplication(t, stub, "LA-1")
+	if loanApplication.RequestedAmount != 250000 || loanApplication.LandID != "LAND-9" {
+		t.Fatalf("expected patched amount and land, got %d and %s", loanApplication.RequestedAmount, loanApplication.LandID)
+	}
+	if loanApplication.PersonalInfo.Mobile != "+61411111111" || loanApplication.PersonalInfo.Lastname != "Doe-LA-1" {
+		t.Fatalf("expected only the mobile to change, got %+v", loanApplication.PersonalInfo)
+	}
+	if loanApplication.TermMonths != 360 || loanApplication.Status != statusSubmitted {
+		t.Fatal("expected fields absent from the patch to be untouched")
+	}
+	if keys, _ := getKeysByPartialCompositeKey(stub, landIndexName, []string{"LAND-LA-1"}); len(keys) != 0 {
+		t.Fatal("expected the old land index entry to be removed")
+	}
+	if keys, _ := getKeysByPartialCompositeKey(stub, landIndexName, []string{"LAND-9"}); len(keys) != 1 {
+		t.Fatal("expected a land index entry for the patched land")
+	}
+}
+
+func TestPatchLoanApplicationRejectsProtectedFields(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	for _, patch := range []string{`{"id":"LA-2"}`, `{"status":"Approved"}`, `{"Status":"Approved"}`, `{"createdBy":"mallory","termMonths":12}`} {
+		_, err := stub.invoke("PatchLoanApplication", "LA-1", patch)
+		if err == nil || !strings.Contains(err.Error(), "cannot be patched") {
+			t.Errorf("expected patch %s to be rejected, got %v", patch, err)
+		}
+	}
+	loanApplication := storedApplication(t, stub, "LA-1")
+	if loanApplication.Status != statusSubmitted || loanApplication.TermMonths != 360 {
+		t.Fatal("expected the application to be unchanged")
+	}
+}
+
+func TestPatchLoanApplicationRevalidates(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	_, err := stub.invoke("PatchLoanApplication", "LA-1", `{"personalInfo":{"email":"not-an-email"}}`)
+	if err == nil || !strings.Contains(err.Error(), "personalInfo.email must be a valid email address") {
+		t.Fatalf("expected the patched record to fail validation, got %v", err)
+	}
+}
+
+func TestPermissionErrorCarriesCaller(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	_, err := stub.as("alice", roleApplicant).invoke("SetLoanParameters", `{}`)
+	var permissionError *PermissionError
+	if !errors.As(err, &permissionError) {
+		t.Fatalf("expected a PermissionError, got %v", err)
+	}
+	if permissionError.Username != "alice" || permissionError.Role != roleApplicant || permissionError.Function != "SetLoanParameters" {
+		t.Fatalf("unexpected permission error fields %+v", permissionError)
+	}
+	if err.Error() != "alice with role Applicant does not have correct permissions for SetLoanParameters" {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+
+	_, err = stub.as("bob", roleReviewer).query("GetLoanApplicationRaw", "LA-1")
+	if !errors.As(err, &permissionError) || permissionError.Username != "bob" || permissionError.Function != "GetLoanApplicationRaw" {
+		t.Fatalf("expected a PermissionError for bob's query, got %v", err)
+	}
+}
+
+func TestCreateLoanApplicationGeneratesSequentialIDs(t *testing.T) {
+	stub := newMockStub()
+	created := func(name string) string {
+		loanApplication := testApplication(name)
+		loanApplication.ID = ""
+		var response createResponse
+		err := json.Unmarshal(createApplication(t, stub, loanApplication), &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.ID
+	}
+
+	assertIDs(t, []string{created("a"), created("b")}, "LN-000001", "LN-000002")
+	createApplication(t, stub, testApplication("LN-000003"))
+	if id := created("c"); id != "LN-000004" {
+		t.Fatalf("expected the generator to skip the client supplied LN-000003, got %s", id)
+	}
+	if storedApplication(t, stub, "LN-000004").PersonalInfo.Lastname != "Doe-c" {
+		t.Fatal("expected the application to be saved under its generated ID")
+	}
+}
+
+func TestCreateLoanApplicationRejectsExistingID(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+
+	other := testApplication("LA-2")
+	input, _ := json.Marshal(other)
+	_, err := stub.invoke("CreateLoanApplication", "LA-1", string(input))
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected an existing ID to be rejected, got %v", err)
+	}
+	if storedApplication(t, stub, "LA-1").PersonalInfo.Lastname != "Doe-LA-1" {
+		t.Fatal("expected the existing application to be untouched")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// MergeApplications Fold the notes, documents and status history of duplicate application args[1] into
+// primary application args[0], leaving the duplicate Merged and pointing at the primary. Duplicates that
+// are approved or have had funds released cannot be merged away.
+func MergeApplications(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering MergeApplications")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected primary and secondary loan application IDs")
+	}
+
+	var primaryID = args[0]
+	var secondaryID = args[1]
+
+	if primaryID == secondaryID {
+		return nil, errors.New("Cannot merge loan application " + primaryID + " into itself")
+	}
+	primary, err := getLoanApplication(stub, primaryID)
+	if err != nil {
+		return nil, err
+	}
+	secondary, err := getLoanApplication(stub, secondaryID)
+	if err != nil {
+		return nil, err
+	}
+	for _, loanApplication := range []LoanApplication{primary, secondary} {
+		if err := ensureUnlocked(loanApplication); err != nil {
+			return nil, err
+		}
+		if terminalStatuses[loanApplication.Status] {
+			return nil, errors.New("Loan application " + loanApplication.ID + " is " + loanApplication.Status + " and cannot be merged")
+		}
+	}
+	if secondary.Status == statusApproved || secondary.DisbursementInfo.DisbursedAmount > 0 {
+		return nil, errors.New("Loan application " + secondaryID + " is approved or partly disbursed and cannot be merged into another")
+	}
+
+	primary.Notes = append(primary.Notes, secondary.Notes...)
+	attached := map[Document]bool{}
+	for _, document := range primary.Documents {
+		attached[document] = true
+	}
+	for _, document := range secondary.Documents {
+		if !attached[document] {
+			primary.Documents = append(primary.Documents, document)
+			attached[document] = true
+		}
+	}
+	// Carried-over changes are tagged with the duplicate they came from so status replays skip them,
+	// and kept in date order so the history still reads chronologically
+	for _, change := range secondary.StatusHistory {
+		if change.MergedFrom == "" {
+			change.MergedFrom = secondaryID
+		}
+		primary.StatusHistory = append(primary.StatusHistory, change)
+	}
+	sort.SliceStable(primary.StatusHistory, func(i, j int) bool {
+		return primary.StatusHistory[i].Date < primary.StatusHistory[j].Date
+	})
+	err = setStatus(stub, &primary, primary.Status, "merged with "+secondaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	original := secondary
+	secondary.MergedInto = primaryID
+	// A merged duplicate no longer sits in a reviewer's queue
+	secondary.ReviewerID = ""
+	err = setStatus(stub, &secondary, statusMerged, "merged into "+primaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveLoanApplication(stub, &primary)
+	if err != nil {
+		return nil, err
+	}
+	err = saveLoanApplication(stub, &secondary)
+	if err != nil {
+		return nil, err
+	}
+	err = removeLoanIndexes(stub, original)
+	if err != nil {
+		return nil, err
+	}
+	err = addLoanIndexes(stub, secondary)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "applicationsMerged", secondaryID+" merged into "+primaryID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully merged loan applications")
+	return nil, nil
+}
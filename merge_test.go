@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMergeApplicationsCombinesRecords(t *testing.T) {
+	stub := newMockStub()
+	primary := testApplication("LA-1")
+	primary.Notes = []Note{{Text: "primary note", Author: "rev1", Date: "2024-03-01T09:00:00Z", Visibility: "internal"}}
+	primary.Documents = []Document{{Type: "payslip", Reference: "DOC-1"}}
+	primary.StatusHistory = []StatusChange{{Status: statusSubmitted, Date: "2024-03-01T09:00:00Z", ChangedBy: "admin"}}
+	seedApplication(t, stub, primary)
+
+	secondary := testApplication("LA-2")
+	secondary.Status = statusUnderReview
+	secondary.ReviewerID = "rev1"
+	secondary.Notes = []Note{{Text: "duplicate note", Author: "rev1", Date: "2024-03-02T09:00:00Z", Visibility: "internal"}}
+	secondary.Documents = []Document{{Type: "payslip", Reference: "DOC-1"}, {Type: "id", Reference: "DOC-2"}}
+	secondary.StatusHistory = []StatusChange{
+		{Status: statusSubmitted, Date: "2024-02-28T09:00:00Z", ChangedBy: "admin"},
+		{Status: statusUnderReview, Date: "2024-03-02T09:00:00Z", ChangedBy: "admin", ReviewerID: "rev1"},
+	}
+	seedApplication(t, stub, secondary)
+
+	stub.mustInvoke(t, "MergeApplications", "LA-1", "LA-2")
+
+	merged := storedApplication(t, stub, "LA-1")
+	if len(merged.Notes) != 2 || merged.Notes[1].Text != "duplicate note" {
+		t.Fatalf("expected both notes on the primary, got %+v", merged.Notes)
+	}
+	if len(merged.Documents) != 2 || merged.Documents[1].Reference != "DOC-2" {
+		t.Fatalf("expected documents combined without duplicates, got %+v", merged.Documents)
+	}
+	// Two carried-over changes plus the primary's own and the merge entry, in date order
+	if len(merged.StatusHistory) != 4 {
+		t.Fatalf("expected 4 status changes, got %+v", merged.StatusHistory)
+	}
+	for i := 1; i < len(merged.StatusHistory); i++ {
+		if merged.StatusHistory[i-1].Date > merged.StatusHistory[i].Date {
+			t.Fatalf("expected status history in date order, got %+v", merged.StatusHistory)
+		}
+	}
+	if merged.StatusHistory[0].MergedFrom != "LA-2" || merged.StatusHistory[1].MergedFrom != "" {
+		t.Fatalf("expected carried-over changes tagged with the duplicate, got %+v", merged.StatusHistory)
+	}
+	if merged.Status != statusSubmitted {
+		t.Fatalf("expected the primary to keep status %s, got %s", statusSubmitted, merged.Status)
+	}
+
+	duplicate := storedApplication(t, stub, "LA-2")
+	if duplicate.Status != statusMerged || duplicate.MergedInto != "LA-1" {
+		t.Fatalf("expected LA-2 merged into LA-1, got %s into %q", duplicate.Status, duplicate.MergedInto)
+	}
+	if duplicate.ReviewerID != "" {
+		t.Fatalf("expected the duplicate to leave the reviewer queue, got %s", duplicate.ReviewerID)
+	}
+	queue, err := getLoanApplicationsByIndex(stub, reviewerIndexName, "rev1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 0 {
+		t.Fatalf("expected no reviewer index entries for rev1, got %d", len(queue))
+	}
+	if lastEventType(t, stub) != "applicationsMerged" {
+		t.Fatalf("expected an applicationsMerged event, got %s", lastEventType(t, stub))
+	}
+}
+
+func TestMergeApplicationsRejectsTerminal(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+	for _, status := range []string{statusRejected, statusMerged} {
+		secondary := testApplication("LA-2")
+		secondary.Status = status
+		seedApplication(t, stub, secondary)
+
+		if _, err := stub.invoke("MergeApplications", "LA-1", "LA-2"); err == nil {
+			t.Fatalf("expected merging a %s application to fail", status)
+		}
+		if _, err := stub.invoke("MergeApplications", "LA-2", "LA-1"); err == nil {
+			t.Fatalf("expected merging into a %s application to fail", status)
+		}
+	}
+
+	approved := testApplication("LA-3")
+	approved.Status = statusApproved
+	seedApplication(t, stub, approved)
+	if _, err := stub.invoke("MergeApplications", "LA-1", "LA-3"); err == nil {
+		t.Fatal("expected merging away an approved application to fail")
+	}
+	if _, err := stub.invoke("MergeApplications", "LA-1", "LA-1"); err == nil {
+		t.Fatal("expected merging an application into itself to fail")
+	}
+	if len(storedApplication(t, stub, "LA-1").StatusHistory) != 0 {
+		t.Fatal("expected a failed merge to leave the primary untouched")
+	}
+}
+
+func TestMergeApplicationsRequiresAdmin(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+	seedApplication(t, stub, testApplication("LA-2"))
+
+	stub.as("rev1", roleReviewer)
+	_, err := stub.invoke("MergeApplications", "LA-1", "LA-2")
+	if _, ok := err.(*PermissionError); !ok {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if storedApplication(t, stub, "LA-2").Status != statusSubmitted {
+		t.Fatal("expected a denied merge to leave the duplicate untouched")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// handlerMetricsKey Ledger key of the per-function invocation counters
+const handlerMetricsKey = "handlerMetrics"
+
+// handlerMetric Invocation count of one function
+type handlerMetric struct {
+	Calls int64 `json:"calls"`
+}
+
+// getHandlerMetrics Load the invocation counters, empty if nothing has been counted yet
+func getHandlerMetrics(stub shim.ChaincodeStubInterface) (map[string]handlerMetric, error) {
+	metrics := map[string]handlerMetric{}
+	_, err := getConfig(stub, handlerMetricsKey, &metrics)
+	return metrics, err
+}
+
+// recordHandlerCall Count a successful invocation of a function
+func recordHandlerCall(stub shim.ChaincodeStubInterface, function string) error {
+	metrics, err := getHandlerMetrics(stub)
+	if err != nil {
+		return err
+	}
+	metric := metrics[function]
+	metric.Calls++
+	metrics[function] = metric
+	return putConfig(stub, handlerMetricsKey, metrics)
+}
+
+// GetHandlerMetrics Get the number of successful invocations of each invoke function.
+// Queries cannot write state and failed invocations discard their writes, so neither is counted;
+// failures are logged by Invoke instead.
+func GetHandlerMetrics(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetHandlerMetrics")
+
+	metrics, err := getHandlerMetrics(stub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(metrics)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// handlerMetricsOf Current invocation counters
+func handlerMetricsOf(t *testing.T, stub *mockStub) map[string]handlerMetric {
+	t.Helper()
+	var metrics map[string]handlerMetric
+	err := json.Unmarshal(stub.mustQuery(t, "GetHandlerMetrics"), &metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metrics
+}
+
+func TestHandlerMetricsCountSuccessfulInvocations(t *testing.T) {
+	stub := newMockStub()
+	if metrics := handlerMetricsOf(t, stub); len(metrics) != 0 {
+		t.Fatalf("expected no counters before any call, got %+v", metrics)
+	}
+
+	createApplication(t, stub, testApplication("LA-1"))
+	createApplication(t, stub, testApplication("LA-2"))
+	stub.mustInvoke(t, "AddTag", "LA-1", "fast-track")
+	if _, err := stub.invoke("AddTag", "LA-1", "fast-track"); err == nil {
+		t.Fatal("expected a duplicate tag to fail")
+	}
+	stub.mustQuery(t, "GetLoanApplication", "LA-1")
+
+	metrics := handlerMetricsOf(t, stub)
+	if metrics["CreateLoanApplication"].Calls != 2 || metrics["AddTag"].Calls != 1 {
+		t.Fatalf("expected 2 creates and 1 successful tag, got %+v", metrics)
+	}
+	if _, ok := metrics["GetLoanApplication"]; ok {
+		t.Fatal("expected queries not to be counted")
+	}
+}
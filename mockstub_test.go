@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// mockEvent An event set by a transaction
+type mockEvent struct {
+	name    string
+	payload []byte
+}
+
+// mockStub In-memory ledger for handler tests. Methods the chaincode does not use are left to the
+// embedded interface, which is nil, so reaching one fails the test loudly.
+type mockStub struct {
+	shim.ChaincodeStubInterface
+	state  map[string][]byte
+	attrs  map[string]string
+	cert   []byte
+	now    time.Time
+	txID   string
+	txSeq  int
+	events []mockEvent
+}
+
+// newMockStub Empty ledger with the clock at a fixed time and an admin caller
+func newMockStub() *mockStub {
+	stub := &mockStub{
+		state: map[string][]byte{},
+		now:   time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC),
+	}
+	return stub.as("admin", roleAdmin)
+}
+
+// as Switch the caller to the given username and role
+func (s *mockStub) as(username string, role string) *mockStub {
+	s.attrs = map[string]string{"username": username, "role": role}
+	return s
+}
+
+func (s *mockStub) GetState(key string) ([]byte, error) {
+	return s.state[key], nil
+}
+
+func (s *mockStub) PutState(key string, value []byte) error {
+	s.state[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (s *mockStub) DelState(key string) error {
+	delete(s.state, key)
+	return nil
+}
+
+// RangeQueryState Iterate keys from startKey inclusive to endKey exclusive in key order
+func (s *mockStub) RangeQueryState(startKey, endKey string) (shim.StateRangeQueryIteratorInterface, error) {
+	iter := &mockIterator{}
+	for key := range s.state {
+		if key >= startKey && key < endKey {
+			iter.keys = append(iter.keys, key)
+		}
+	}
+	sort.Strings(iter.keys)
+	for _, key := range iter.keys {
+		iter.values = append(iter.values, s.state[key])
+	}
+	return iter, nil
+}
+
+// mockIterator Range query results captured when the query started
+type mockIterator struct {
+	keys   []string
+	values [][]byte
+}
+
+func (it *mockIterator) HasNext() bool {
+	return len(it.keys) > 0
+}
+
+func (it *mockIterator) Next() (string, []byte, error) {
+	key, value := it.keys[0], it.values[0]
+	it.keys, it.values = it.keys[1:], it.values[1:]
+	return key, value, nil
+}
+
+func (it *mockIterator) Close() error {
+	return nil
+}
+
+func (s *mockStub) ReadCertAttribute(attributeName string) ([]byte, error) {
+	return []byte(s.attrs[attributeName]), nil
+}
+
+func (s *mockStub) GetCallerCertificate() ([]byte, error) {
+	return s.cert, nil
+}
+
+func (s *mockStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: s.now.Unix(), Nanos: int32(s.now.Nanosecond())}, nil
+}
+
+func (s *mockStub) GetTxID() string {
+	return s.txID
+}
+
+func (s *mockStub) SetEvent(name string, payload []byte) error {
+	s.events = append(s.events, mockEvent{name: name, payload: payload})
+	return nil
+}
+
+// invoke Run an invocation as its own transaction, discarding its writes and events when it fails
+func (s *mockStub) invoke(function string, args ...string) ([]byte, error) {
+	s.txSeq++
+	s.txID = "tx" + strconv.Itoa(s.txSeq)
+	state := map[string][]byte{}
+	for key, value := range s.state {
+		state[key] = value
+	}
+	events := len(s.events)
+
+	result, err := new(SampleChainCode).Invoke(s, function, args)
+	if err != nil {
+		s.state = state
+		s.events = s.events[:events]
+	}
+	return result, err
+}
+
+// query Run a query against the current ledger
+func (s *mockStub) query(function string, args ...string) ([]byte, error) {
+	s.txSeq++
+	s.txID = "tx" + strconv.Itoa(s.txSeq)
+	return new(SampleChainCode).Query(s, function, args)
+}
+
+// mustInvoke Run an invocation, failing the test if it errors
+func (s *mockStub) mustInvoke(t *testing.T, function string, args ...string) []byte {
+	t.Helper()
+	result, err := s.invoke(function, args...)
+	if err != nil {
+		t.Fatalf("%s(%v) failed: %v", function, args, err)
+	}
+	return result
+}
+
+// mustQuery Run a query, failing the test if it errors
+func (s *mockStub) mustQuery(t *testing.T, function string, args ...string) []byte {
+	t.Helper()
+	result, err := s.query(function, args...)
+	if err != nil {
+		t.Fatalf("%s(%v) failed: %v", function, args, err)
+	}
+	return result
+}
+
+// lastEvent The most recent event set, failing the test if there is none
+func (s *mockStub) lastEvent(t *testing.T) mockEvent {
+	t.Helper()
+	if len(s.events) == 0 {
+		t.Fatal("expected an event")
+	}
+	return s.events[len(s.events)-1]
+}
+
+// testApplication A loan application passing every default validation rule
+func testApplication(id string) LoanApplication {
+	return LoanApplication{
+		ID:         id,
+		PropertyID: "PROP-" + id,
+		LandID:     "LAND-" + id,
+		PermitID:   "PERMIT-" + id,
+		BuyerID:    "BUYER-" + id,
+		PersonalInfo: PersonalInfo{
+			Firstname: "Jane",
+			Lastname:  "Doe-" + id,
+			DOB:       "1985-06-01",
+			Email:     id + "@example.com",
+			Mobile:    "+61400000000",
+		},
+		FinancialInfo: FinancialInfo{
+			MonthlySalary: 10000,
+			MonthlyRent:   2000,
+		},
+		RequestedAmount: 300000,
+		TermMonths:      360,
+		Currency:        "USD",
+		FairMarketValue: 500000,
+		CreditTier:      "A",
+		Status:          statusSubmitted,
+	}
+}
+
+// createApplication Create an application through CreateLoanApplication as an admin, restoring the caller afterwards
+func createApplication(t *testing.T, stub *mockStub, loanApplication LoanApplication) []byte {
+	t.Helper()
+	attrs := stub.attrs
+	defer func() { stub.attrs = attrs }()
+	stub.as("admin", roleAdmin)
+
+	input, err := json.Marshal(loanApplication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stub.mustInvoke(t, "CreateLoanApplication", loanApplication.ID, string(input))
+}
+
+// seedApplication Write an application and its index entries directly, for states handlers cannot easily reach
+func seedApplication(t *testing.T, stub *mockStub, loanApplication LoanApplication) {
+	t.Helper()
+	if loanApplication.CreatedDate == "" {
+		loanApplication.CreatedDate = stub.now.Format(time.RFC3339)
+	}
+	if loanApplication.LastModifiedDate == "" {
+		loanApplication.LastModifiedDate = loanApplication.CreatedDate
+	}
+	err := putLoanApplication(stub, &loanApplication, stub.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = addLoanIndexes(stub, loanApplication)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// storedApplication Read an application straight from the ledger
+func storedApplication(t *testing.T, stub *mockStub, loanAppID string) LoanApplication {
+	t.Helper()
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loanApplication
+}
+
+// decodeRecords Unmarshal a JSON array of loan application records
+func decodeRecords(t *testing.T, bytes []byte) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	err := json.Unmarshal(bytes, &records)
+	if err != nil {
+		t.Fatalf("could not unmarshal records %s: %v", bytes, err)
+	}
+	return records
+}
+
+// recordIDs IDs of a JSON array of loan application records in response order
+func recordIDs(t *testing.T, bytes []byte) []string {
+	t.Helper()
+	ids := []string{}
+	for _, record := range decodeRecords(t, bytes) {
+		id, _ := record["id"].(string)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// assertIDs Fail unless ids are exactly the expected IDs in order
+func assertIDs(t *testing.T, ids []string, expected ...string) {
+	t.Helper()
+	if len(ids) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, ids)
+		}
+	}
+}
+
+// assertSameIDs Fail unless ids hold exactly the expected IDs in any order
+func assertSameIDs(t *testing.T, ids []string, expected ...string) {
+	t.Helper()
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	want := append([]string(nil), expected...)
+	sort.Strings(want)
+	assertIDs(t, sorted, want...)
+}
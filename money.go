@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// errMonetaryOverflow Returned when a monetary sum would not fit in an int64
+var errMonetaryOverflow = errors.New("Monetary amount overflows int64")
+
+// addMoney Add two monetary amounts, erroring instead of wrapping on overflow
+func addMoney(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, errMonetaryOverflow
+	}
+	return a + b, nil
+}
+
+// sumMoney Add any number of monetary amounts, erroring instead of wrapping on overflow
+func sumMoney(amounts ...int64) (int64, error) {
+	var total int64
+	for _, amount := range amounts {
+		var err error
+		total, err = addMoney(total, amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// monthlyOutgoings Total of an applicant's monthly rent, expenditure and loan payments
+func monthlyOutgoings(info FinancialInfo) (int64, error) {
+	return sumMoney(info.MonthlyRent, info.OtherExpenditure, info.MonthlyLoanPayment)
+}
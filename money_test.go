@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestSumMoneyBoundary(t *testing.T) {
+	total, err := sumMoney(math.MaxInt64-10, 10)
+	if err != nil || total != math.MaxInt64 {
+		t.Fatalf("expected MaxInt64, got %d, %v", total, err)
+	}
+	if _, err := sumMoney(math.MaxInt64-10, 10, 1); err != errMonetaryOverflow {
+		t.Fatalf("expected overflow, got %v", err)
+	}
+	if _, err := sumMoney(math.MinInt64, -1); err != errMonetaryOverflow {
+		t.Fatalf("expected negative overflow, got %v", err)
+	}
+}
+
+func TestCreateLoanApplicationRejectsOverflowingOutgoings(t *testing.T) {
+	stub := newMockStub()
+	loanApplication := testApplication("LA-1")
+	loanApplication.FinancialInfo.MonthlyRent = math.MaxInt64 / 2
+	loanApplication.FinancialInfo.OtherExpenditure = math.MaxInt64 / 2
+	input, _ := json.Marshal(loanApplication)
+	if _, err := stub.invoke("CreateLoanApplication", "LA-1", string(input)); err != nil {
+		t.Fatalf("expected outgoings just under the limit to be accepted, got %v", err)
+	}
+
+	loanApplication = testApplication("LA-2")
+	loanApplication.FinancialInfo.MonthlyRent = math.MaxInt64 / 2
+	loanApplication.FinancialInfo.OtherExpenditure = math.MaxInt64 / 2
+	loanApplication.FinancialInfo.MonthlyLoanPayment = 2
+	input, _ = json.Marshal(loanApplication)
+	if _, err := stub.invoke("CreateLoanApplication", "LA-2", string(input)); err == nil {
+		t.Fatal("expected overflowing outgoings to be rejected")
+	}
+}
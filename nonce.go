@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Operations protected by a replay nonce
+const (
+	nonceOpApprove  = "approve"
+	nonceOpDisburse = "disburse"
+)
+
+// nonceKey Ledger key holding the nonces already used for an operation on an application
+func nonceKey(loanAppID string, op string) string {
+	return "nonce_" + loanAppID + "_" + op
+}
+
+// consumeNonce Record a nonce for an operation on an application, rejecting one that has been used before
+func consumeNonce(stub shim.ChaincodeStubInterface, loanAppID string, op string, nonce string) error {
+	if nonce == "" {
+		return errors.New("A nonce is required to " + op + " loan application " + loanAppID)
+	}
+
+	var used []string
+	_, err := getConfig(stub, nonceKey(loanAppID, op), &used)
+	if err != nil {
+		return err
+	}
+	for _, u := range used {
+		if u == nonce {
+			logger.Error("Replayed " + op + " nonce for loan application " + loanAppID)
+			return errors.New("Nonce " + nonce + " has already been used to " + op + " loan application " + loanAppID)
+		}
+	}
+	return putConfig(stub, nonceKey(loanAppID, op), append(used, nonce))
+}
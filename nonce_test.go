@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplayedApprovalIsRejected(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+	stub.as("rev1", roleReviewer)
+	stub.mustInvoke(t, "ApproveLoanApplication", "LA-1", "", "", "nonce-1")
+
+	_, err := stub.invoke("ApproveLoanApplication", "LA-1", "", "", "nonce-1")
+	if err == nil || !strings.Contains(err.Error(), "Nonce nonce-1 has already been used to approve") {
+		t.Fatalf("expected the replayed approval to be rejected, got %v", err)
+	}
+	if _, err := stub.invoke("ApproveLoanApplication", "LA-1", "", "", ""); err == nil {
+		t.Fatal("expected an approval without a nonce to be rejected")
+	}
+}
+
+func TestReplayedDisbursementIsRejected(t *testing.T) {
+	stub := newMockStub()
+	seedApproved(t, stub, "LA-1", 300000)
+	stub.mustInvoke(t, "DisburseLoanApplication", "LA-1", "1000", "nonce-1")
+
+	_, err := stub.invoke("DisburseLoanApplication", "LA-1", "1000", "nonce-1")
+	if err == nil || !strings.Contains(err.Error(), "has already been used to disburse") {
+		t.Fatalf("expected the replayed tranche to be rejected, got %v", err)
+	}
+	stub.mustInvoke(t, "DisburseLoanApplication", "LA-1", "1000", "nonce-2")
+	if disbursed := storedApplication(t, stub, "LA-1").DisbursementInfo.DisbursedAmount; disbursed != 2000 {
+		t.Fatalf("expected two tranches of 1000, got %d", disbursed)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Note visibilities
+const (
+	visibilityInternal  = "internal"
+	visibilityApplicant = "applicant"
+)
+
+// Note schema
+type Note struct {
+	Text       string `json:"text"`
+	Author     string `json:"author"`
+	Date       string `json:"date"`
+	Visibility string `json:"visibility"`
+}
+
+// AddNote Add note args[1] to application args[0] with optional visibility args[2], internal by default
+func AddNote(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering AddNote")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID and note text")
+	}
+
+	var loanAppID = args[0]
+	var text = args[1]
+	var visibility = visibilityInternal
+	if len(args) > 2 && args[2] != "" {
+		visibility = args[2]
+	}
+
+	if text == "" {
+		return nil, errors.New("Note text cannot be empty")
+	}
+	if visibility != visibilityInternal && visibility != visibilityApplicant {
+		return nil, errors.New("Note visibility must be " + visibilityInternal + " or " + visibilityApplicant)
+	}
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	username, _ := GetCertAttribute(stub, "username")
+
+	loanApplication.Notes = append(loanApplication.Notes, Note{
+		Text:       text,
+		Author:     username,
+		Date:       now.Format(time.RFC3339),
+		Visibility: visibility,
+	})
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully added note to loan application")
+	return nil, nil
+}
+
+// visibleNotes Filter notes to those the caller's role may see; only bank staff see internal notes
+func visibleNotes(stub shim.ChaincodeStubInterface, notes []Note) []Note {
+	return filterNotes(notes, checkRole(stub, "view internal notes", roleAdmin, roleReviewer) == nil)
+}
+
+// filterNotes Keep internal notes only for staff
+func filterNotes(notes []Note, staff bool) []Note {
+	visible := []Note{}
+	for _, note := range notes {
+		if staff || note.Visibility == visibilityApplicant {
+			visible = append(visible, note)
+		}
+	}
+	return visible
+}
+
+// GetLoanApplicationNotes Get the notes on application args[0] visible to the caller
+func GetLoanApplicationNotes(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationNotes")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	loanApplication, err := getLoanApplication(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(visibleNotes(stub, loanApplication.Notes))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// noteTexts Texts of notes in a response
+func noteTexts(t *testing.T, bytes []byte) []string {
+	t.Helper()
+	var notes []Note
+	err := json.Unmarshal(bytes, &notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := []string{}
+	for _, note := range notes {
+		texts = append(texts, note.Text)
+	}
+	return texts
+}
+
+func TestNoteVisibility(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+	stub.as("rev1", roleReviewer)
+	stub.mustInvoke(t, "AddNote", "LA-1", "income looks inflated")
+	stub.mustInvoke(t, "AddNote", "LA-1", "please upload a payslip", visibilityApplicant)
+
+	assertIDs(t, noteTexts(t, stub.mustQuery(t, "GetLoanApplicationNotes", "LA-1")), "income looks inflated", "please upload a payslip")
+
+	stub.as("app1", roleApplicant)
+	assertIDs(t, noteTexts(t, stub.mustQuery(t, "GetLoanApplicationNotes", "LA-1")), "please upload a payslip")
+
+	// The internal note must not leak through other reads either
+	var record LoanApplication
+	err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplication", "LA-1"), &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Notes) != 1 || record.Notes[0].Visibility != visibilityApplicant {
+		t.Fatalf("expected only the applicant note on the record, got %+v", record.Notes)
+	}
+	var projected [][]Note
+	err = json.Unmarshal(stub.mustQuery(t, "ProjectField", "notes"), &projected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projected) != 1 || len(projected[0]) != 1 {
+		t.Fatalf("expected only the applicant note to be projected, got %+v", projected)
+	}
+}
+
+func TestAddNoteRejectsUnknownVisibility(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	if _, err := stub.invoke("AddNote", "LA-1", "hello", "public"); err == nil {
+		t.Fatal("expected an unknown visibility to be rejected")
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	creditTierRatesKey = "creditTierRatesBps"
+	loanParametersKey  = "loanParameters"
+	decisionCodesKey   = "decisionCodes"
+)
+
+// defaultDecisionCodes Decision codes in force until an admin sets the list
+var defaultDecisionCodes = []string{"DTI_TOO_HIGH", "INCOMPLETE_DOCS", "LTV_EXCEEDED"}
+
+// LoanParameters Admin-managed rules applied across loan applications
+type LoanParameters struct {
+	RequiredDocumentTypes          []string           `json:"requiredDocumentTypes"`
+	RequiredConsentTypes           []string           `json:"requiredConsentTypes"`
+	RejectDuplicates               bool               `json:"rejectDuplicates"`
+	ArchiveRetentionDays           int                `json:"archiveRetentionDays"`
+	PIIRetentionDays               int                `json:"piiRetentionDays"`
+	MaxDebtToIncome                float64            `json:"maxDebtToIncome"`
+	MaxLoanToValue                 float64            `json:"maxLoanToValue"`
+	MinApplicantAge                int                `json:"minApplicantAge"`
+	MaxApplicantAge                int                `json:"maxApplicantAge"`
+	AutoApproveThreshold           int64              `json:"autoApproveThreshold"`
+	RiskWeights                    RiskWeights        `json:"riskWeights"`
+	MinApprovedAmount              int64              `json:"minApprovedAmount"`
+	MaxOpenApplicationsPerProperty int                `json:"maxOpenApplicationsPerProperty"`
+	AppraisalValidityDays          int                `json:"appraisalValidityDays"`
+	ApprovalValidityDays           int                `json:"approvalValidityDays"`
+	ApplicationExpiryDays          int                `json:"applicationExpiryDays"`
+	ExpiryGraceDays                int                `json:"expiryGraceDays"`
+	ReviewSLAHours                 int                `json:"reviewSlaHours"`
+	MinTermMonths                  int                `json:"minTermMonths"`
+	MaxTermMonths                  int                `json:"maxTermMonths"`
+	MinInterestRateBps             int                `json:"minInterestRateBps"`
+	MaxInterestRateBps             int                `json:"maxInterestRateBps"`
+	InterestRateStepBps            int                `json:"interestRateStepBps"`
+	RoundingPolicy                 string             `json:"roundingPolicy"`
+	DefaultCurrency                string             `json:"defaultCurrency"`
+	FXRates                        map[string]float64 `json:"fxRates"`
+}
+
+// getLoanParameters Load the loan parameters, zero valued if none have been set
+func getLoanParameters(stub shim.ChaincodeStubInterface) (LoanParameters, error) {
+	var params LoanParameters
+	_, err := getConfig(stub, loanParametersKey, &params)
+	return params, err
+}
+
+// SetLoanParameters Replace the loan parameters with the JSON in args[0]
+func SetLoanParameters(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetLoanParameters")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan parameters argument")
+	}
+
+	var params LoanParameters
+	err := json.Unmarshal([]byte(args[0]), &params)
+	if err != nil {
+		logger.Error("Could not unmarshal loan parameters", err)
+		return nil, errors.New("Invalid loan parameters: " + err.Error())
+	}
+	err = validateRiskWeights(params.RiskWeights)
+	if err != nil {
+		return nil, err
+	}
+	err = validateFXRates(params)
+	if err != nil {
+		return nil, err
+	}
+	if params.MinInterestRateBps < 0 || params.MaxInterestRateBps < 0 || params.InterestRateStepBps < 0 {
+		return nil, errors.New("Interest rate bounds and step cannot be negative")
+	}
+	if !validRoundingPolicies[params.RoundingPolicy] {
+		return nil, errors.New("Unknown rounding policy '" + params.RoundingPolicy + "', expected " + roundingHalfUp + ", " + roundingBankers + " or " + roundingFloor)
+	}
+
+	err = putConfig(stub, loanParametersKey, &params)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully saved loan parameters")
+	return nil, nil
+}
+
+// GetLoanParameters Get the current loan parameters
+func GetLoanParameters(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanParameters")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&params)
+}
+
+// getCreditTierRates Load the credit tier to interest rate table in basis points
+func getCreditTierRates(stub shim.ChaincodeStubInterface) (map[string]int, error) {
+	rates := map[string]int{}
+	_, err := getConfig(stub, creditTierRatesKey, &rates)
+	return rates, err
+}
+
+// SetCreditTierRates Replace the credit tier to interest rate table with the JSON object of percentages in args[0],
+// stored as basis points rounded to the configured step
+func SetCreditTierRates(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetCreditTierRates")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected credit tier rates argument")
+	}
+
+	var percentages map[string]json.Number
+	err := json.Unmarshal([]byte(args[0]), &percentages)
+	if err != nil {
+		logger.Error("Could not unmarshal credit tier rates", err)
+		return nil, errors.New("Invalid credit tier rates: " + err.Error())
+	}
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	rates := map[string]int{}
+	for tier, percentage := range percentages {
+		if tier == "" {
+			return nil, errors.New("Invalid rate for credit tier " + tier)
+		}
+		rates[tier], err = parseRateBps(percentage.String(), params)
+		if err != nil {
+			return nil, errors.New("Invalid rate for credit tier " + tier + ": " + err.Error())
+		}
+	}
+
+	err = putConfig(stub, creditTierRatesKey, rates)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully saved credit tier rates")
+	return nil, nil
+}
+
+// GetCreditTierRates Get the credit tier to interest rate table as percentages
+func GetCreditTierRates(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetCreditTierRates")
+
+	rates, err := getCreditTierRates(stub)
+	if err != nil {
+		return nil, err
+	}
+	percentages := map[string]float64{}
+	for tier, rate := range rates {
+		percentages[tier] = bpsToPercent(rate)
+	}
+	return json.Marshal(percentages)
+}
+
+// getDecisionCodes Load the allowed decision codes
+func getDecisionCodes(stub shim.ChaincodeStubInterface) ([]string, error) {
+	var codes []string
+	found, err := getConfig(stub, decisionCodesKey, &codes)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return defaultDecisionCodes, nil
+	}
+	return codes, nil
+}
+
+// validateDecisionCode Ensure a decision code is in the allowed list
+func validateDecisionCode(stub shim.ChaincodeStubInterface, code string) error {
+	codes, err := getDecisionCodes(stub)
+	if err != nil {
+		return err
+	}
+	for _, c := range codes {
+		if c == code {
+			return nil
+		}
+	}
+	return errors.New("Unknown decision code '" + code + "'")
+}
+
+// SetDecisionCodes Replace the allowed decision codes with the JSON array in args[0]
+func SetDecisionCodes(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetDecisionCodes")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected decision codes argument")
+	}
+
+	var codes []string
+	err := json.Unmarshal([]byte(args[0]), &codes)
+	if err != nil {
+		logger.Error("Could not unmarshal decision codes", err)
+		return nil, errors.New("Invalid decision codes: " + err.Error())
+	}
+	for _, code := range codes {
+		if code == "" {
+			return nil, errors.New("Decision codes cannot be empty")
+		}
+	}
+
+	err = putConfig(stub, decisionCodesKey, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully saved decision codes")
+	return nil, nil
+}
+
+// GetDecisionCodes Get the allowed decision codes
+func GetDecisionCodes(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetDecisionCodes")
+
+	codes, err := getDecisionCodes(stub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(codes)
+}
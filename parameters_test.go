@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRejectValidatesDecisionCode(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetDecisionCodes", `["DTI_TOO_HIGH","FRAUD_SUSPECTED"]`)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+	stub.as("rev1", roleReviewer)
+
+	_, err := stub.invoke("RejectLoanApplication", "LA-1", "LTV_EXCEEDED", "too high")
+	if err == nil || !strings.Contains(err.Error(), "Unknown decision code 'LTV_EXCEEDED'") {
+		t.Fatalf("expected a code outside the taxonomy to be rejected, got %v", err)
+	}
+
+	stub.mustInvoke(t, "RejectLoanApplication", "LA-1", "FRAUD_SUSPECTED", "identity mismatch")
+	loanApplication := storedApplication(t, stub, "LA-1")
+	if loanApplication.Status != statusRejected || loanApplication.DecisionCode != "FRAUD_SUSPECTED" {
+		t.Fatalf("expected rejection with FRAUD_SUSPECTED, got %s with %s", loanApplication.Status, loanApplication.DecisionCode)
+	}
+	if codes := string(stub.mustQuery(t, "GetDecisionCodes")); codes != `["DTI_TOO_HIGH","FRAUD_SUSPECTED"]` {
+		t.Fatalf("expected the configured codes, got %s", codes)
+	}
+}
+
+func TestSetDecisionCodesRejectsEmptyCode(t *testing.T) {
+	stub := newMockStub()
+
+	if _, err := stub.invoke("SetDecisionCodes", `["DTI_TOO_HIGH",""]`); err == nil {
+		t.Fatal("expected an empty code to be rejected")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Rounding policies for fractional monetary results
+const (
+	roundingHalfUp  = "half-up"
+	roundingBankers = "bankers"
+	roundingFloor   = "floor"
+)
+
+// validRoundingPolicies Policies accepted in the loan parameters, empty meaning half-up
+var validRoundingPolicies = map[string]bool{
+	"":              true,
+	roundingHalfUp:  true,
+	roundingBankers: true,
+	roundingFloor:   true,
+}
+
+// roundMoney Round a fractional monetary amount to a whole amount under the given policy
+func roundMoney(amount float64, policy string) int64 {
+	switch policy {
+	case roundingBankers:
+		return int64(math.RoundToEven(amount))
+	case roundingFloor:
+		return int64(math.Floor(amount))
+	default:
+		return int64(math.Floor(amount + 0.5))
+	}
+}
+
+// bpsPerMonthlyPeriod Basis points in a whole rate times the twelve monthly periods of a year
+const bpsPerMonthlyPeriod = 10000 * 12
+
+// monthlyInterest Interest accrued on a balance over one month at an annual rate in basis points
+func monthlyInterest(balance int64, annualRateBps int, policy string) int64 {
+	return roundMoney(float64(balance*int64(annualRateBps))/bpsPerMonthlyPeriod, policy)
+}
+
+// monthlyPayment Level monthly repayment of a principal over a term at an annual rate in basis points
+func monthlyPayment(principal int64, annualRateBps int, termMonths int, policy string) (int64, error) {
+	if termMonths <= 0 {
+		return 0, errors.New("Term must be a positive number of months")
+	}
+	if annualRateBps < 0 {
+		return 0, errors.New("Interest rate cannot be negative")
+	}
+
+	if annualRateBps == 0 {
+		return roundMoney(float64(principal)/float64(termMonths), policy), nil
+	}
+	monthlyRate := float64(annualRateBps) / bpsPerMonthlyPeriod
+	growth := math.Pow(1+monthlyRate, float64(termMonths))
+	return roundMoney(float64(principal)*monthlyRate*growth/(growth-1), policy), nil
+}
+
+// paymentQuote Monthly repayment for an application and how it was rounded
+type paymentQuote struct {
+	ID              string  `json:"id"`
+	Principal       int64   `json:"principal"`
+	InterestRate    float64 `json:"interestRate"`
+	InterestRateBps int     `json:"interestRateBps"`
+	TermMonths      int     `json:"termMonths"`
+	MonthlyPayment  int64   `json:"monthlyPayment"`
+	RoundingPolicy  string  `json:"roundingPolicy"`
+}
+
+// CalculateMonthlyPayment Get the monthly repayment for application args[0], using the approved amount once set
+func CalculateMonthlyPayment(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering CalculateMonthlyPayment")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var loanAppID = args[0]
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	principal := loanApplication.ApprovedAmount
+	if principal == 0 {
+		principal = loanApplication.RequestedAmount
+	}
+	policy := params.RoundingPolicy
+	if policy == "" {
+		policy = roundingHalfUp
+	}
+
+	payment, err := monthlyPayment(principal, loanApplication.InterestRateBps, loanApplication.TermMonths, policy)
+	if err != nil {
+		return nil, errors.New("Loan application " + loanAppID + ": " + err.Error())
+	}
+
+	return json.Marshal(paymentQuote{
+		ID:              loanAppID,
+		Principal:       principal,
+		InterestRate:    bpsToPercent(loanApplication.InterestRateBps),
+		InterestRateBps: loanApplication.InterestRateBps,
+		TermMonths:      loanApplication.TermMonths,
+		MonthlyPayment:  payment,
+		RoundingPolicy:  policy,
+	})
+}
+
+// ScheduleRow One period of an amortization schedule
+type ScheduleRow struct {
+	Period    int   `json:"period"`
+	Payment   int64 `json:"payment"`
+	Principal int64 `json:"principal"`
+	Interest  int64 `json:"interest"`
+	Balance   int64 `json:"balance"`
+}
+
+// GenerateAmortizationSchedule Split level monthly repayments into principal and interest for each period,
+// rounding under policy. The final payment absorbs accumulated rounding so the balance ends at exactly zero.
+func GenerateAmortizationSchedule(principal int64, annualRateBps int, termMonths int, policy string) ([]ScheduleRow, error) {
+	payment, err := monthlyPayment(principal, annualRateBps, termMonths, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := []ScheduleRow{}
+	balance := principal
+	for period := 1; period <= termMonths; period++ {
+		interest := monthlyInterest(balance, annualRateBps, policy)
+		principalPortion := payment - interest
+		if period == termMonths || principalPortion > balance {
+			principalPortion = balance
+		}
+		balance -= principalPortion
+		schedule = append(schedule, ScheduleRow{
+			Period:    period,
+			Payment:   principalPortion + interest,
+			Principal: principalPortion,
+			Interest:  interest,
+			Balance:   balance,
+		})
+		if balance == 0 {
+			break
+		}
+	}
+	return schedule, nil
+}
+
+// GetAmortizationSchedule Get the repayment schedule of application args[0], using the approved amount once set
+func GetAmortizationSchedule(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetAmortizationSchedule")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var loanAppID = args[0]
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	principal := loanApplication.ApprovedAmount
+	if principal == 0 {
+		principal = loanApplication.RequestedAmount
+	}
+	schedule, err := GenerateAmortizationSchedule(principal, loanApplication.InterestRateBps, loanApplication.TermMonths, params.RoundingPolicy)
+	if err != nil {
+		return nil, errors.New("Loan application " + loanAppID + ": " + err.Error())
+	}
+
+	bytes, err := json.Marshal(schedule)
+	if err != nil {
+		logger.Error("Could not marshal amortization schedule", err)
+		return nil, err
+	}
+	return bytes, nil
+}
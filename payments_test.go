@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRoundingPolicies(t *testing.T) {
+	cases := []struct {
+		policy      string
+		payment     int64
+		halfPayment int64
+		interest    int64
+		rateBps     int
+	}{
+		{roundingHalfUp, 3, 4, 1, 525},
+		{roundingBankers, 2, 4, 0, 524},
+		{roundingFloor, 2, 3, 0, 524},
+	}
+	for _, c := range cases {
+		if payment, _ := monthlyPayment(10, 0, 4, c.policy); payment != c.payment {
+			t.Errorf("%s: expected 10 over 4 months to be %d, got %d", c.policy, c.payment, payment)
+		}
+		if payment, _ := monthlyPayment(14, 0, 4, c.policy); payment != c.halfPayment {
+			t.Errorf("%s: expected 14 over 4 months to be %d, got %d", c.policy, c.halfPayment, payment)
+		}
+		if interest := monthlyInterest(100, 600, c.policy); interest != c.interest {
+			t.Errorf("%s: expected a month's interest on 100 at 6%% to be %d, got %d", c.policy, c.interest, interest)
+		}
+		if rateBps, _ := parseRateBps("5.245", LoanParameters{RoundingPolicy: c.policy}); rateBps != c.rateBps {
+			t.Errorf("%s: expected 5.245%% to be %d bps, got %d", c.policy, c.rateBps, rateBps)
+		}
+	}
+}
+
+func TestCalculateMonthlyPaymentUsesRoundingPolicy(t *testing.T) {
+	stub := newMockStub()
+	loanApplication := testApplication("LA-1")
+	loanApplication.RequestedAmount = 10
+	loanApplication.TermMonths = 4
+	seedApplication(t, stub, loanApplication)
+
+	for policy, expected := range map[string]int64{roundingHalfUp: 3, roundingBankers: 2, roundingFloor: 2} {
+		stub.mustInvoke(t, "SetLoanParameters", `{"roundingPolicy":"`+policy+`"}`)
+		var quote paymentQuote
+		err := json.Unmarshal(stub.mustQuery(t, "CalculateMonthlyPayment", "LA-1"), &quote)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if quote.MonthlyPayment != expected || quote.RoundingPolicy != policy {
+			t.Errorf("expected %d under %s, got %+v", expected, policy, quote)
+		}
+	}
+	if _, err := stub.invoke("SetLoanParameters", `{"roundingPolicy":"half-down"}`); err == nil {
+		t.Fatal("expected an unknown rounding policy to be rejected")
+	}
+}
+
+func TestGenerateAmortizationScheduleSumsToPrincipal(t *testing.T) {
+	cases := []struct {
+		principal  int64
+		rateBps    int
+		termMonths int
+	}{
+		{300000, 525, 360},
+		{100000, 0, 7},
+		{12345, 999, 12},
+		{1000, 1800, 1},
+	}
+	for _, c := range cases {
+		for _, policy := range []string{roundingHalfUp, roundingBankers, roundingFloor} {
+			schedule, err := GenerateAmortizationSchedule(c.principal, c.rateBps, c.termMonths, policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(schedule) != c.termMonths {
+				t.Errorf("%+v %s: expected %d periods, got %d", c, policy, c.termMonths, len(schedule))
+			}
+			var principal int64
+			balance := c.principal
+			for _, row := range schedule {
+				principal += row.Principal
+				balance -= row.Principal
+				if row.Payment != row.Principal+row.Interest || row.Balance != balance {
+					t.Fatalf("%+v %s: inconsistent row %+v", c, policy, row)
+				}
+			}
+			if principal != c.principal || schedule[len(schedule)-1].Balance != 0 {
+				t.Errorf("%+v %s: expected repayments of %d ending at zero, got %d ending at %d", c, policy, c.principal, principal, schedule[len(schedule)-1].Balance)
+			}
+		}
+	}
+}
+
+func TestGetAmortizationScheduleUsesApprovedTerms(t *testing.T) {
+	stub := newMockStub()
+	loanApplication := testApplication("LA-1")
+	loanApplication.Status = statusApproved
+	loanApplication.ApprovedAmount = 240000
+	loanApplication.InterestRateBps = 600
+	loanApplication.TermMonths = 240
+	seedApplication(t, stub, loanApplication)
+
+	var schedule []ScheduleRow
+	err := json.Unmarshal(stub.mustQuery(t, "GetAmortizationSchedule", "LA-1"), &schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, _ := monthlyPayment(240000, 600, 240, roundingHalfUp)
+	if len(schedule) != 240 || schedule[0].Payment != payment || schedule[0].Interest != 1200 || schedule[239].Balance != 0 {
+		t.Fatalf("expected 240 level payments of %d starting with 1200 interest, got first %+v last %+v", payment, schedule[0], schedule[len(schedule)-1])
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const productSchemasKey = "productSchemas"
+
+// loanApplicationFields Flattened dotted field paths and values of an application
+func loanApplicationFields(loanApplication LoanApplication) (map[string]interface{}, error) {
+	laBytes, err := json.Marshal(&loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	return renderedFields(laBytes)
+}
+
+// isEmptyField Report whether a flattened JSON value holds nothing
+func isEmptyField(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// getProductSchemas Load the product type to required fields mapping
+func getProductSchemas(stub shim.ChaincodeStubInterface) (map[string][]string, error) {
+	schemas := map[string][]string{}
+	_, err := getConfig(stub, productSchemasKey, &schemas)
+	return schemas, err
+}
+
+// validateProduct Check an application's product type is known and its required fields are set.
+// Nothing is enforced until product schemas have been configured.
+func validateProduct(stub shim.ChaincodeStubInterface, loanApplication LoanApplication) ([]string, error) {
+	schemas, err := getProductSchemas(stub)
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 {
+		return nil, nil
+	}
+
+	required, ok := schemas[loanApplication.ProductType]
+	if !ok {
+		return []string{"productType '" + loanApplication.ProductType + "' is not a known product"}, nil
+	}
+	fields, err := loanApplicationFields(loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, field := range required {
+		if isEmptyField(fields[field]) {
+			violations = append(violations, field+" is required for product "+loanApplication.ProductType)
+		}
+	}
+	return violations, nil
+}
+
+// SetProductSchemas Replace the product type to required fields mapping with the JSON object in args[0]
+func SetProductSchemas(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetProductSchemas")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected product schemas argument")
+	}
+
+	var schemas map[string][]string
+	err := json.Unmarshal([]byte(args[0]), &schemas)
+	if err != nil {
+		logger.Error("Could not unmarshal product schemas", err)
+		return nil, errors.New("Invalid product schemas: " + err.Error())
+	}
+
+	known, err := loanApplicationFields(LoanApplication{})
+	if err != nil {
+		return nil, err
+	}
+	var unknown []string
+	for product, fields := range schemas {
+		if product == "" {
+			return nil, errors.New("Product type cannot be empty")
+		}
+		for _, field := range fields {
+			if _, ok := known[field]; !ok {
+				unknown = append(unknown, product+": "+field)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, errors.New("Unknown loan application fields in product schemas: " + strings.Join(unknown, ", "))
+	}
+
+	err = putConfig(stub, productSchemasKey, schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully saved product schemas")
+	return nil, nil
+}
+
+// GetProductSchemas Get the product type to required fields mapping
+func GetProductSchemas(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetProductSchemas")
+
+	schemas, err := getProductSchemas(stub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(schemas)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestProductSchemasRequiredFields(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetProductSchemas", `{"personal":["requestedAmount"],"construction":["PermitID","SalesContractID","appraisalDate"]}`)
+
+	// The same applicant data is complete for one product and not for the other
+	personal := testApplication("LA-1")
+	personal.ProductType = "personal"
+	createApplication(t, stub, personal)
+
+	construction := testApplication("LA-2")
+	construction.ProductType = "construction"
+	input, _ := json.Marshal(construction)
+	_, err := stub.invoke("CreateLoanApplication", "LA-2", string(input))
+	if err == nil || !strings.Contains(err.Error(), "SalesContractID is required for product construction; appraisalDate is required for product construction") {
+		t.Fatalf("expected the construction product to require its fields, got %v", err)
+	}
+
+	unknown := testApplication("LA-3")
+	unknown.ProductType = "bridging"
+	input, _ = json.Marshal(unknown)
+	if _, err := stub.invoke("CreateLoanApplication", "LA-3", string(input)); err == nil {
+		t.Fatal("expected an unknown product to be rejected")
+	}
+}
+
+func TestSetProductSchemasRejectsUnknownFields(t *testing.T) {
+	stub := newMockStub()
+
+	_, err := stub.invoke("SetProductSchemas", `{"personal":["salary"]}`)
+	if err == nil || !strings.Contains(err.Error(), "personal: salary") {
+		t.Fatalf("expected an unknown field to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,980 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Pending action labels
+const (
+	pendingActionAssignReviewer    = "AssignReviewer"
+	pendingActionSecondApproval    = "SecondApproval"
+	pendingActionSatisfyConditions = "SatisfyConditions"
+)
+
+// Sort orders
+const (
+	sortOrderAsc  = "asc"
+	sortOrderDesc = "desc"
+)
+
+// loanApplicationLess Ordering functions for each sortable field
+var loanApplicationLess = map[string]func(a, b LoanApplication) bool{
+	"requestedAmount": func(a, b LoanApplication) bool {
+		return a.RequestedAmount < b.RequestedAmount
+	},
+	"lastModifiedDate": func(a, b LoanApplication) bool {
+		return a.LastModifiedDate < b.LastModifiedDate
+	},
+	"status": func(a, b LoanApplication) bool {
+		return a.Status < b.Status
+	},
+}
+
+// GetLoanApplicationsWithPendingActions Get applications awaiting admin action
+func GetLoanApplicationsWithPendingActions(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsWithPendingActions")
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []LoanApplication{}
+	var actions []map[string]interface{}
+	for _, loanApplication := range loanApplications {
+		var action string
+		switch {
+		case loanApplication.Status == statusSubmitted && loanApplication.ReviewerID == "":
+			action = pendingActionAssignReviewer
+		case loanApplication.Status == statusPendingSecondApproval:
+			action = pendingActionSecondApproval
+		case loanApplication.Status == statusConditionallyApproved:
+			action = pendingActionSatisfyConditions
+		default:
+			continue
+		}
+		pending = append(pending, loanApplication)
+		actions = append(actions, map[string]interface{}{"pendingAction": action})
+	}
+
+	views, err := viewLoanApplications(stub, pending, actions)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(views)
+}
+
+// ListAllLoanApplications List every application, optionally sorted by args[0] in args[1] order
+func ListAllLoanApplications(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering ListAllLoanApplications")
+
+	var sortBy, sortOrder string
+	if len(args) > 0 {
+		sortBy = args[0]
+	}
+	sortOrder = sortOrderAsc
+	if len(args) > 1 && args[1] != "" {
+		sortOrder = args[1]
+	}
+
+	var less func(a, b LoanApplication) bool
+	if sortBy != "" {
+		var ok bool
+		less, ok = loanApplicationLess[sortBy]
+		if !ok {
+			logger.Error("Invalid sort field " + sortBy)
+			return nil, errors.New("Cannot sort by " + sortBy + ", expected one of requestedAmount, lastModifiedDate or status")
+		}
+	}
+	if sortOrder != sortOrderAsc && sortOrder != sortOrderDesc {
+		logger.Error("Invalid sort order " + sortOrder)
+		return nil, errors.New("Sort order must be " + sortOrderAsc + " or " + sortOrderDesc)
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	if less != nil {
+		sort.SliceStable(loanApplications, func(i, j int) bool {
+			if sortOrder == sortOrderDesc {
+				return less(loanApplications[j], loanApplications[i])
+			}
+			return less(loanApplications[i], loanApplications[j])
+		})
+	}
+
+	return marshalLoanApplications(stub, loanApplications)
+}
+
+// ExportLoanApplications Export applications as newline-delimited JSON, optionally only those with status args[0]
+func ExportLoanApplications(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering ExportLoanApplications")
+
+	var status string
+	if len(args) > 0 {
+		status = args[0]
+	}
+
+	view, err := loanApplicationViewer(stub)
+	if err != nil {
+		return nil, err
+	}
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines [][]byte
+	for _, loanApplication := range loanApplications {
+		if status != "" && loanApplication.Status != status {
+			continue
+		}
+		laBytes, err := view(loanApplication)
+		if err != nil {
+			logger.Error("Could not marshal loan application "+loanApplication.ID+" for export", err)
+			return nil, err
+		}
+		lines = append(lines, laBytes)
+	}
+	return bytes.Join(lines, []byte("\n")), nil
+}
+
+// piiFields Top-level fields only privileged callers may project
+var piiFields = map[string]bool{
+	"personalInfo": true,
+}
+
+// ProjectField Get the values of field args[0] across all applications
+func ProjectField(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering ProjectField")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing field name")
+	}
+
+	var field = args[0]
+
+	// Marshal the zero value so the known fields always track the struct tags
+	var known map[string]json.RawMessage
+	zeroBytes, _ := json.Marshal(LoanApplication{})
+	json.Unmarshal(zeroBytes, &known)
+	if _, ok := known[field]; !ok {
+		logger.Error("Unknown field " + field)
+		return nil, errors.New("Unknown loan application field " + field)
+	}
+	if piiFields[field] {
+		if err := checkRole(stub, "ProjectField of "+field, roleAdmin); err != nil {
+			return nil, err
+		}
+	}
+
+	consented, err := piiConsentChecker(stub)
+	if err != nil {
+		return nil, err
+	}
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []json.RawMessage{}
+	for _, loanApplication := range loanApplications {
+		if piiFields[field] && !consented(loanApplication) {
+			values = append(values, json.RawMessage("null"))
+			continue
+		}
+		if field == "notes" {
+			loanApplication.Notes = visibleNotes(stub, loanApplication.Notes)
+		}
+		laBytes, err := json.Marshal(&loanApplication)
+		if err != nil {
+			logger.Error("Could not marshal loan application "+loanApplication.ID, err)
+			return nil, err
+		}
+		var fields map[string]json.RawMessage
+		err = json.Unmarshal(laBytes, &fields)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, fields[field])
+	}
+
+	bytes, err := json.Marshal(values)
+	if err != nil {
+		logger.Error("Could not marshal projected values", err)
+		return nil, err
+	}
+	return bytes, nil
+}
+
+// QueryByStatuses Get applications whose status is any of the comma-separated statuses in args[0]
+func QueryByStatuses(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering QueryByStatuses")
+
+	if len(args) < 1 || args[0] == "" {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected comma-separated list of statuses")
+	}
+
+	statuses := map[string]bool{}
+	for _, status := range strings.Split(args[0], ",") {
+		status = strings.TrimSpace(status)
+		if !validStatuses[status] {
+			logger.Error("Invalid status " + status)
+			return nil, errors.New("Unknown loan application status '" + status + "'")
+		}
+		statuses[status] = true
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if statuses[loanApplication.Status] {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// healthCheckKey Reserved key read by the health check, never written
+const healthCheckKey = "healthCheck"
+
+// healthStatus Health check response
+type healthStatus struct {
+	Status string `json:"status"`
+	TxID   string `json:"txId"`
+}
+
+// HealthCheck Confirm the chaincode and its stub are responsive
+func HealthCheck(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering HealthCheck")
+
+	_, err := stub.GetState(healthCheckKey)
+	if err != nil {
+		logger.Error("Health check could not read from ledger", err)
+		return nil, err
+	}
+	return json.Marshal(healthStatus{Status: "healthy", TxID: stub.GetTxID()})
+}
+
+// GetLoanApplicationsRequiringReappraisal Get pre-disbursement applications whose appraisal has gone stale
+func GetLoanApplicationsRequiringReappraisal(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsRequiringReappraisal")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	if params.AppraisalValidityDays <= 0 {
+		return nil, errors.New("Appraisal validity period is not configured")
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := now.AddDate(0, 0, -params.AppraisalValidityDays)
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	stale := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if !preDisbursementStatuses[loanApplication.Status] {
+			continue
+		}
+		appraised, err := time.Parse(dateLayout, loanApplication.AppraisalDate)
+		if err != nil {
+			continue
+		}
+		if appraised.Before(cutoff) {
+			stale = append(stale, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, stale)
+}
+
+// GetLoanApplicationsByAgeRange Get applications whose applicant is aged between args[0] and args[1] inclusive
+func GetLoanApplicationsByAgeRange(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsByAgeRange")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected minimum and maximum age")
+	}
+
+	minAge, err := strconv.Atoi(args[0])
+	if err != nil || minAge < 0 {
+		return nil, errors.New("Minimum age must be a non-negative integer")
+	}
+	maxAge, err := strconv.Atoi(args[1])
+	if err != nil || maxAge < minAge {
+		return nil, errors.New("Maximum age must be an integer no less than the minimum age")
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		age, err := applicantAge(loanApplication, now)
+		if err != nil {
+			continue
+		}
+		if age >= minAge && age <= maxAge {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// GetApplicationsExceedingDTI Get approved applications whose debt-to-income exceeds args[0],
+// or the configured maximum when no threshold is given
+func GetApplicationsExceedingDTI(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationsExceedingDTI")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	threshold := params.MaxDebtToIncome
+	if len(args) > 0 && args[0] != "" {
+		threshold, err = strconv.ParseFloat(args[0], 64)
+		if err != nil || threshold <= 0 {
+			return nil, errors.New("Debt-to-income threshold must be a positive number")
+		}
+	}
+	if threshold <= 0 {
+		return nil, errors.New("No debt-to-income threshold given or configured")
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	exceeding := []LoanApplication{}
+	var ratios []map[string]interface{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.Status != statusApproved && loanApplication.Status != statusDisbursed {
+			continue
+		}
+		if dti := debtToIncome(loanApplication); dti > threshold {
+			exceeding = append(exceeding, loanApplication)
+			ratios = append(ratios, map[string]interface{}{"debtToIncome": dti})
+		}
+	}
+
+	views, err := viewLoanApplications(stub, exceeding, ratios)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(views)
+}
+
+// GetLoanApplicationsWithoutReviewer Get applications under review that have lost their reviewer
+func GetLoanApplicationsWithoutReviewer(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsWithoutReviewer")
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	orphaned := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.Status == statusUnderReview && loanApplication.ReviewerID == "" {
+			orphaned = append(orphaned, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, orphaned)
+}
+
+// GetLoanApplicationsByCurrency Get applications denominated in ISO currency args[0]
+func GetLoanApplicationsByCurrency(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsByCurrency")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing currency code")
+	}
+
+	var currency = strings.ToUpper(args[0])
+	if !validCurrencies[currency] {
+		return nil, errors.New("Unsupported currency code '" + args[0] + "'")
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.Currency == currency {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// GetApplicationsByRiskScoreRange Get applications whose risk score is between args[0] and args[1] inclusive
+func GetApplicationsByRiskScoreRange(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationsByRiskScoreRange")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected minimum and maximum risk score")
+	}
+
+	minScore, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, errors.New("Minimum risk score must be an integer")
+	}
+	maxScore, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, errors.New("Maximum risk score must be an integer")
+	}
+	if minScore < 0 || minScore > maxScore || maxScore > 100 {
+		return nil, errors.New("Risk score range must satisfy 0 <= min <= max <= 100")
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.RiskScore >= minScore && loanApplication.RiskScore <= maxScore {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// GetLoanApplicationsCreatedByRole Get how many applications were created by each role
+func GetLoanApplicationsCreatedByRole(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsCreatedByRole")
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, loanApplication := range loanApplications {
+		counts[loanApplication.CreatedByRole]++
+	}
+	return json.Marshal(counts)
+}
+
+// danglingReferences An application together with the referenced records that no longer exist
+type danglingReferences struct {
+	ID      string   `json:"id"`
+	Missing []string `json:"missing"`
+}
+
+// GetApplicationsMissingReferences Get applications whose property, land, permit or sales contract records are missing
+func GetApplicationsMissingReferences(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationsMissingReferences")
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []danglingReferences{}
+	for _, loanApplication := range loanApplications {
+		references := []struct {
+			field string
+			id    string
+		}{
+			{"PropertyID", loanApplication.PropertyID},
+			{"LandID", loanApplication.LandID},
+			{"PermitID", loanApplication.PermitID},
+			{"SalesContractID", loanApplication.SalesContractID},
+		}
+
+		var missing []string
+		for _, reference := range references {
+			if reference.id == "" {
+				continue
+			}
+			bytes, err := stub.GetState(reference.id)
+			if err != nil {
+				logger.Error("Could not fetch "+reference.field+" "+reference.id+" from ledger", err)
+				return nil, err
+			}
+			if bytes == nil {
+				missing = append(missing, reference.field+" "+reference.id)
+			}
+		}
+		if len(missing) > 0 {
+			results = append(results, danglingReferences{ID: loanApplication.ID, Missing: missing})
+		}
+	}
+
+	bytes, err := json.Marshal(results)
+	if err != nil {
+		logger.Error("Could not marshal dangling references", err)
+		return nil, err
+	}
+	return bytes, nil
+}
+
+// GetLoanApplicationsByDecisionCode Get applications decided with decision code args[0]
+func GetLoanApplicationsByDecisionCode(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsByDecisionCode")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing decision code")
+	}
+
+	var decisionCode = args[0]
+	err := validateDecisionCode(stub, decisionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.DecisionCode == decisionCode {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// maxDateRangeDays Longest date range the daily time-series queries will report on
+const maxDateRangeDays = 366
+
+// GetApplicationsSubmittedPerDay Get the number of applications created on each day from args[0] to args[1] inclusive
+func GetApplicationsSubmittedPerDay(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationsSubmittedPerDay")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected start and end date")
+	}
+
+	start, err := time.Parse(dateLayout, args[0])
+	if err != nil {
+		return nil, errors.New("Start date must be in " + dateLayout + " format")
+	}
+	end, err := time.Parse(dateLayout, args[1])
+	if err != nil {
+		return nil, errors.New("End date must be in " + dateLayout + " format")
+	}
+	if end.Before(start) {
+		return nil, errors.New("End date cannot be before start date")
+	}
+	if end.Sub(start) >= maxDateRangeDays*24*time.Hour {
+		return nil, errors.New("Date range cannot exceed " + strconv.Itoa(maxDateRangeDays) + " days")
+	}
+
+	// Every day in the range is reported, including those with no submissions
+	counts := map[string]int{}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		counts[day.Format(dateLayout)] = 0
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+	for _, loanApplication := range loanApplications {
+		created, err := time.Parse(time.RFC3339, loanApplication.CreatedDate)
+		if err != nil {
+			continue
+		}
+		day := created.UTC().Format(dateLayout)
+		if _, ok := counts[day]; ok {
+			counts[day]++
+		}
+	}
+
+	return json.Marshal(counts)
+}
+
+// turnaroundMetrics Time from creation to first decision across decided applications, in business hours
+type turnaroundMetrics struct {
+	Decided      int     `json:"decided"`
+	AverageHours float64 `json:"averageHours"`
+	MinHours     float64 `json:"minHours"`
+	MaxHours     float64 `json:"maxHours"`
+}
+
+// GetApprovalTurnaroundMetrics Get the average, minimum and maximum business hours from creation to approval or rejection
+func GetApprovalTurnaroundMetrics(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApprovalTurnaroundMetrics")
+
+	calendar, err := getBusinessCalendar(stub)
+	if err != nil {
+		return nil, err
+	}
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := turnaroundMetrics{}
+	var total float64
+	for _, loanApplication := range loanApplications {
+		created, err := time.Parse(time.RFC3339, loanApplication.CreatedDate)
+		if err != nil {
+			continue
+		}
+		for _, change := range ownStatusHistory(loanApplication) {
+			if change.Status != statusApproved && change.Status != statusRejected {
+				continue
+			}
+			decided, err := time.Parse(time.RFC3339, change.Date)
+			if err != nil {
+				break
+			}
+			hours := calendar.businessHoursBetween(created, decided)
+			if metrics.Decided == 0 || hours < metrics.MinHours {
+				metrics.MinHours = hours
+			}
+			if metrics.Decided == 0 || hours > metrics.MaxHours {
+				metrics.MaxHours = hours
+			}
+			total += hours
+			metrics.Decided++
+			break
+		}
+	}
+	// With no decided applications every metric is reported as zero
+	if metrics.Decided > 0 {
+		metrics.AverageHours = total / float64(metrics.Decided)
+	}
+
+	return json.Marshal(metrics)
+}
+
+// GetLoanApplicationsByFairMarketValueRange Get applications whose fair market value is between args[0] and args[1]
+// inclusive. Unappraised applications, with a zero value, are only included when args[2] is "true".
+func GetLoanApplicationsByFairMarketValueRange(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsByFairMarketValueRange")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected minimum and maximum fair market value")
+	}
+
+	minValue, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || minValue < 0 {
+		return nil, errors.New("Minimum fair market value must be a non-negative integer")
+	}
+	maxValue, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || maxValue < minValue {
+		return nil, errors.New("Maximum fair market value must be an integer no less than the minimum")
+	}
+	includeUnappraised := false
+	if len(args) > 2 && args[2] != "" {
+		includeUnappraised, err = strconv.ParseBool(args[2])
+		if err != nil {
+			return nil, errors.New("Include unappraised flag must be true or false")
+		}
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.FairMarketValue == 0 {
+			if includeUnappraised {
+				matches = append(matches, loanApplication)
+			}
+			continue
+		}
+		if loanApplication.FairMarketValue >= minValue && loanApplication.FairMarketValue <= maxValue {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// SearchLoanApplications Get applications whose applicant first or last name contains args[0], ignoring case.
+// This scans every application and returns unredacted records, so it is restricted to bank staff.
+func SearchLoanApplications(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SearchLoanApplications")
+
+	if len(args) < 1 || strings.TrimSpace(args[0]) == "" {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing search text")
+	}
+
+	var search = strings.ToLower(strings.TrimSpace(args[0]))
+
+	consented, err := piiConsentChecker(stub)
+	if err != nil {
+		return nil, err
+	}
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		// Applicants who have not consented are not searchable by name
+		if !consented(loanApplication) {
+			continue
+		}
+		info := loanApplication.PersonalInfo
+		if strings.Contains(strings.ToLower(info.Firstname), search) || strings.Contains(strings.ToLower(info.Lastname), search) {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// GetTerminalApplications Get applications that have reached a terminal status
+func GetTerminalApplications(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetTerminalApplications")
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if terminalStatuses[loanApplication.Status] {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// loanApplicationFilter Criteria for QueryLoanApplications; unset criteria match everything
+type loanApplicationFilter struct {
+	Status     string `json:"status"`
+	BuyerID    string `json:"buyerId"`
+	ReviewerID string `json:"reviewerId"`
+	MinAmount  *int64 `json:"minAmount"`
+	MaxAmount  *int64 `json:"maxAmount"`
+}
+
+// matches Report whether an application satisfies every set criterion
+func (f loanApplicationFilter) matches(loanApplication LoanApplication) bool {
+	return (f.Status == "" || loanApplication.Status == f.Status) &&
+		(f.BuyerID == "" || loanApplication.BuyerID == f.BuyerID) &&
+		(f.ReviewerID == "" || loanApplication.ReviewerID == f.ReviewerID) &&
+		(f.MinAmount == nil || loanApplication.RequestedAmount >= *f.MinAmount) &&
+		(f.MaxAmount == nil || loanApplication.RequestedAmount <= *f.MaxAmount)
+}
+
+// QueryLoanApplications Get applications matching every criterion in the JSON filter args[0]
+func QueryLoanApplications(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering QueryLoanApplications")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing filter argument")
+	}
+
+	var filter loanApplicationFilter
+	decoder := json.NewDecoder(strings.NewReader(args[0]))
+	// Misspelt criteria would otherwise be ignored and match everything
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&filter)
+	if err != nil {
+		logger.Error("Could not unmarshal filter", err)
+		return nil, errors.New("Invalid filter: " + err.Error())
+	}
+	if filter.Status != "" && !validStatuses[filter.Status] {
+		return nil, errors.New("Unknown status '" + filter.Status + "'")
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return nil, errors.New("Minimum amount cannot exceed maximum amount")
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if filter.matches(loanApplication) {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// GetApplicationsByTermRange Get applications whose term is between args[0] and args[1] months inclusive
+func GetApplicationsByTermRange(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationsByTermRange")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected minimum and maximum term in months")
+	}
+
+	minTerm, err := strconv.Atoi(args[0])
+	if err != nil || minTerm < 1 {
+		return nil, errors.New("Minimum term must be a positive integer")
+	}
+	maxTerm, err := strconv.Atoi(args[1])
+	if err != nil || maxTerm < minTerm {
+		return nil, errors.New("Maximum term must be an integer no less than the minimum term")
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.TermMonths >= minTerm && loanApplication.TermMonths <= maxTerm {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// GetApplicationsByInterestRateRange Get applications whose interest rate is between percentages args[0] and args[1]
+// inclusive, converted to basis points as configured rates are
+func GetApplicationsByInterestRateRange(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationsByInterestRateRange")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected minimum and maximum interest rate")
+	}
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	minRate, err := parseRateBps(args[0], params)
+	if err != nil {
+		return nil, errors.New("Minimum interest rate: " + err.Error())
+	}
+	maxRate, err := parseRateBps(args[1], params)
+	if err != nil {
+		return nil, errors.New("Maximum interest rate: " + err.Error())
+	}
+	if minRate > maxRate {
+		return nil, errors.New("Interest rate range must satisfy 0 <= min <= max")
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.InterestRateBps >= minRate && loanApplication.InterestRateBps <= maxRate {
+			matches = append(matches, loanApplication)
+		}
+	}
+
+	return marshalLoanApplications(stub, matches)
+}
+
+// GetApplicationStateCountsOverTime Get how many applications held each status at the end of every day from
+// args[0] to args[1] inclusive, replayed from status history. Purged applications are not counted.
+func GetApplicationStateCountsOverTime(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationStateCountsOverTime")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected start and end date")
+	}
+
+	start, err := time.Parse(dateLayout, args[0])
+	if err != nil {
+		return nil, errors.New("Start date must be in " + dateLayout + " format")
+	}
+	end, err := time.Parse(dateLayout, args[1])
+	if err != nil {
+		return nil, errors.New("End date must be in " + dateLayout + " format")
+	}
+	if end.Before(start) {
+		return nil, errors.New("End date cannot be before start date")
+	}
+	if end.Sub(start) >= maxDateRangeDays*24*time.Hour {
+		return nil, errors.New("Date range cannot exceed " + strconv.Itoa(maxDateRangeDays) + " days")
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]map[string]int{}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		endOfDay := day.AddDate(0, 0, 1)
+		dayCounts := map[string]int{}
+		for _, loanApplication := range loanApplications {
+			// Status history is appended in transaction order, so the last change before the
+			// end of the day is the status held then
+			var status string
+			for _, change := range ownStatusHistory(loanApplication) {
+				changed, err := time.Parse(time.RFC3339, change.Date)
+				if err != nil || !changed.Before(endOfDay) {
+					break
+				}
+				status = change.Status
+			}
+			if status != "" {
+				dayCounts[status]++
+			}
+		}
+		counts[day.Format(dateLayout)] = dayCounts
+	}
+
+	return json.Marshal(counts)
+}
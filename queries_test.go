@@ -0,0 +1,640 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetLoanApplicationsWithPendingActions(t *testing.T) {
+	stub := newMockStub()
+
+	unassigned := testApplication("LA-1")
+	seedApplication(t, stub, unassigned)
+
+	assigned := testApplication("LA-2")
+	assigned.ReviewerID = "rev1"
+	seedApplication(t, stub, assigned)
+
+	secondApproval := testApplication("LA-3")
+	secondApproval.Status = statusPendingSecondApproval
+	secondApproval.ReviewerID = "rev1"
+	seedApplication(t, stub, secondApproval)
+
+	conditional := testApplication("LA-4")
+	conditional.Status = statusConditionallyApproved
+	conditional.ReviewerID = "rev1"
+	seedApplication(t, stub, conditional)
+
+	approved := testApplication("LA-5")
+	approved.Status = statusApproved
+	seedApplication(t, stub, approved)
+
+	records := decodeRecords(t, stub.mustQuery(t, "GetLoanApplicationsWithPendingActions"))
+	actions := map[string]interface{}{}
+	for _, record := range records {
+		actions[record["id"].(string)] = record["pendingAction"]
+	}
+	expected := map[string]string{
+		"LA-1": pendingActionAssignReviewer,
+		"LA-3": pendingActionSecondApproval,
+		"LA-4": pendingActionSatisfyConditions,
+	}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected pending actions %v, got %v", expected, actions)
+	}
+	for id, action := range expected {
+		if actions[id] != action {
+			t.Errorf("expected %s pending %s, got %v", id, action, actions[id])
+		}
+	}
+}
+
+func TestGetLoanApplicationsWithPendingActionsEmpty(t *testing.T) {
+	stub := newMockStub()
+
+	approved := testApplication("LA-1")
+	approved.Status = statusApproved
+	seedApplication(t, stub, approved)
+
+	result := stub.mustQuery(t, "GetLoanApplicationsWithPendingActions")
+	if string(result) != "[]" {
+		t.Fatalf("expected an empty array, got %s", result)
+	}
+}
+
+func TestGetLoanApplicationsWithPendingActionsRequiresAdmin(t *testing.T) {
+	stub := newMockStub().as("rev1", roleReviewer)
+
+	_, err := stub.query("GetLoanApplicationsWithPendingActions")
+	if _, ok := err.(*PermissionError); !ok {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+}
+
+func TestListAllLoanApplicationsSorted(t *testing.T) {
+	stub := newMockStub()
+	for id, amount := range map[string]int64{"LA-1": 200000, "LA-2": 100000, "LA-3": 300000} {
+		loanApplication := testApplication(id)
+		loanApplication.RequestedAmount = amount
+		seedApplication(t, stub, loanApplication)
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "ListAllLoanApplications", "requestedAmount")), "LA-2", "LA-1", "LA-3")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "ListAllLoanApplications", "requestedAmount", "asc")), "LA-2", "LA-1", "LA-3")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "ListAllLoanApplications", "requestedAmount", "desc")), "LA-3", "LA-1", "LA-2")
+}
+
+func TestListAllLoanApplicationsInvalidSort(t *testing.T) {
+	stub := newMockStub()
+
+	if _, err := stub.query("ListAllLoanApplications", "firstname"); err == nil {
+		t.Error("expected an unknown sort field to be rejected")
+	}
+	if _, err := stub.query("ListAllLoanApplications", "status", "sideways"); err == nil {
+		t.Error("expected an unknown sort order to be rejected")
+	}
+}
+
+func TestExportLoanApplicationsLineCount(t *testing.T) {
+	stub := newMockStub()
+	for _, id := range []string{"LA-1", "LA-2", "LA-3"} {
+		seedApplication(t, stub, testApplication(id))
+	}
+	rejected := testApplication("LA-4")
+	rejected.Status = statusRejected
+	seedApplication(t, stub, rejected)
+
+	lines := bytes.Split(stub.mustQuery(t, "ExportLoanApplications"), []byte("\n"))
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		var loanApplication LoanApplication
+		if err := json.Unmarshal(line, &loanApplication); err != nil {
+			t.Fatalf("line %s is not a loan application: %v", line, err)
+		}
+	}
+
+	lines = bytes.Split(stub.mustQuery(t, "ExportLoanApplications", statusSubmitted), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 Submitted lines, got %d", len(lines))
+	}
+}
+
+func TestExportLoanApplicationsRequiresStaff(t *testing.T) {
+	stub := newMockStub().as("app1", roleApplicant)
+
+	_, err := stub.query("ExportLoanApplications")
+	if _, ok := err.(*PermissionError); !ok {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+}
+
+func TestProjectFieldNumeric(t *testing.T) {
+	stub := newMockStub().as("rev1", roleReviewer)
+	for id, amount := range map[string]int64{"LA-1": 100000, "LA-2": 250000} {
+		loanApplication := testApplication(id)
+		loanApplication.RequestedAmount = amount
+		seedApplication(t, stub, loanApplication)
+	}
+
+	var values []int64
+	err := json.Unmarshal(stub.mustQuery(t, "ProjectField", "requestedAmount"), &values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[0] != 100000 || values[1] != 250000 {
+		t.Fatalf("expected [100000 250000], got %v", values)
+	}
+}
+
+func TestProjectFieldRejectsPIIForNonAdmins(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	stub.as("rev1", roleReviewer)
+	_, err := stub.query("ProjectField", "personalInfo")
+	if _, ok := err.(*PermissionError); !ok {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+
+	stub.as("admin", roleAdmin)
+	var values []PersonalInfo
+	err = json.Unmarshal(stub.mustQuery(t, "ProjectField", "personalInfo"), &values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 || values[0].Lastname != "Doe-LA-1" {
+		t.Fatalf("expected the admin to see personal information, got %v", values)
+	}
+}
+
+func TestProjectFieldUnknownField(t *testing.T) {
+	stub := newMockStub()
+
+	if _, err := stub.query("ProjectField", "salary"); err == nil {
+		t.Fatal("expected an unknown field to be rejected")
+	}
+}
+
+func TestQueryByStatusesUnion(t *testing.T) {
+	stub := newMockStub()
+	statuses := map[string]string{"LA-1": statusSubmitted, "LA-2": statusApproved, "LA-3": statusRejected, "LA-4": statusApproved}
+	for id, status := range statuses {
+		loanApplication := testApplication(id)
+		loanApplication.Status = status
+		seedApplication(t, stub, loanApplication)
+	}
+
+	ids := recordIDs(t, stub.mustQuery(t, "QueryByStatuses", "Approved, Submitted,Approved"))
+	assertIDs(t, ids, "LA-1", "LA-2", "LA-4")
+}
+
+func TestQueryByStatusesUnknownStatus(t *testing.T) {
+	stub := newMockStub()
+
+	if _, err := stub.query("QueryByStatuses", "Approved,Pending"); err == nil {
+		t.Fatal("expected an unknown status to be rejected")
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	stub := newMockStub().as("anyone", roleApplicant)
+
+	var status healthStatus
+	err := json.Unmarshal(stub.mustQuery(t, "HealthCheck"), &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != "healthy" || status.TxID != stub.txID {
+		t.Fatalf("expected healthy in %s, got %+v", stub.txID, status)
+	}
+}
+
+func TestGetLoanApplicationsRequiringReappraisal(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"appraisalValidityDays":90}`)
+
+	fresh := testApplication("LA-1")
+	fresh.AppraisalDate = stub.now.AddDate(0, 0, -89).Format(dateLayout)
+	seedApplication(t, stub, fresh)
+	stale := testApplication("LA-2")
+	stale.AppraisalDate = stub.now.AddDate(0, 0, -120).Format(dateLayout)
+	seedApplication(t, stub, stale)
+	staleDisbursed := testApplication("LA-3")
+	staleDisbursed.Status = statusDisbursed
+	staleDisbursed.AppraisalDate = stale.AppraisalDate
+	seedApplication(t, stub, staleDisbursed)
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsRequiringReappraisal")), "LA-2")
+}
+
+func TestGetLoanApplicationsByAgeRangeBoundaries(t *testing.T) {
+	stub := newMockStub()
+	dobs := map[string]string{
+		"LA-1": "1984-03-15", // turns 40 today
+		"LA-2": "1984-03-16", // still 39
+		"LA-3": "1973-03-16", // 50 until tomorrow
+		"LA-4": "1973-03-15", // turns 51 today
+	}
+	for id, dob := range dobs {
+		loanApplication := testApplication(id)
+		loanApplication.PersonalInfo.DOB = dob
+		seedApplication(t, stub, loanApplication)
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByAgeRange", "40", "50")), "LA-1", "LA-3")
+	if _, err := stub.query("GetLoanApplicationsByAgeRange", "50", "40"); err == nil {
+		t.Fatal("expected an inverted range to be rejected")
+	}
+}
+
+func TestGetApplicationsExceedingDTI(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"maxDebtToIncome":0.4}`)
+	seeds := []struct {
+		id      string
+		status  string
+		payment int64
+	}{
+		{"LA-1", statusApproved, 5000},
+		{"LA-2", statusApproved, 3000},
+		{"LA-3", statusDisbursed, 4500},
+		{"LA-4", statusSubmitted, 6000},
+		{"LA-5", statusApproved, 4000},
+	}
+	for _, seed := range seeds {
+		loanApplication := testApplication(seed.id)
+		loanApplication.Status = seed.status
+		loanApplication.FinancialInfo.MonthlyLoanPayment = seed.payment
+		seedApplication(t, stub, loanApplication)
+	}
+
+	records := decodeRecords(t, stub.mustQuery(t, "GetApplicationsExceedingDTI"))
+	if len(records) != 2 || records[0]["id"] != "LA-1" || records[1]["id"] != "LA-3" {
+		t.Fatalf("expected LA-1 and LA-3 above the configured maximum, got %v", records)
+	}
+	if records[0]["debtToIncome"] != 0.5 || records[1]["debtToIncome"] != 0.45 {
+		t.Fatalf("expected ratios 0.5 and 0.45, got %v and %v", records[0]["debtToIncome"], records[1]["debtToIncome"])
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsExceedingDTI", "0.25")), "LA-1", "LA-2", "LA-3", "LA-5")
+}
+
+func TestGetLoanApplicationsWithoutReviewer(t *testing.T) {
+	stub := newMockStub()
+	orphaned := testApplication("LA-1")
+	orphaned.Status = statusUnderReview
+	seedApplication(t, stub, orphaned)
+	seedUnderReview(t, stub, testApplication("LA-2"))
+	seedApplication(t, stub, testApplication("LA-3"))
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsWithoutReviewer")), "LA-1")
+}
+
+func TestGetLoanApplicationsByCurrency(t *testing.T) {
+	stub := newMockStub()
+	for id, currency := range map[string]string{"LA-1": "USD", "LA-2": "EUR", "LA-3": "USD", "LA-4": "GBP"} {
+		loanApplication := testApplication(id)
+		loanApplication.Currency = currency
+		seedApplication(t, stub, loanApplication)
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByCurrency", "usd")), "LA-1", "LA-3")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByCurrency", "EUR")), "LA-2")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByCurrency", "JPY")))
+	if _, err := stub.query("GetLoanApplicationsByCurrency", "XYZ"); err == nil {
+		t.Fatal("expected an unsupported currency to be rejected")
+	}
+}
+
+func TestGetApplicationsByRiskScoreRange(t *testing.T) {
+	stub := newMockStub()
+	for id, score := range map[string]int{"LA-1": 10, "LA-2": 30, "LA-3": 31, "LA-4": 60, "LA-5": 61, "LA-6": 95} {
+		loanApplication := testApplication(id)
+		loanApplication.RiskScore = score
+		seedApplication(t, stub, loanApplication)
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByRiskScoreRange", "0", "30")), "LA-1", "LA-2")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByRiskScoreRange", "31", "60")), "LA-3", "LA-4")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByRiskScoreRange", "61", "100")), "LA-5", "LA-6")
+	for _, band := range [][]string{{"-1", "10"}, {"50", "40"}, {"0", "101"}} {
+		if _, err := stub.query("GetApplicationsByRiskScoreRange", band...); err == nil {
+			t.Errorf("expected band %v to be rejected", band)
+		}
+	}
+}
+
+func TestGetLoanApplicationsCreatedByRole(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+	createApplication(t, stub, testApplication("LA-2"))
+	applicantOwned := testApplication("LA-3")
+	applicantOwned.CreatedBy = "app1"
+	applicantOwned.CreatedByRole = roleApplicant
+	applicantOwned.BuyerID = "app1"
+	seedApplication(t, stub, applicantOwned)
+	stub.as("app1", roleApplicant).mustInvoke(t, "CloneLoanApplication", "LA-3", "LA-4")
+	seedApplication(t, stub, testApplication("LA-5"))
+
+	var counts map[string]int
+	err := json.Unmarshal(stub.as("admin", roleAdmin).mustQuery(t, "GetLoanApplicationsCreatedByRole"), &counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{roleAdmin: 2, roleApplicant: 2, "": 1}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected counts %v, got %v", expected, counts)
+	}
+	for role, count := range expected {
+		if counts[role] != count {
+			t.Errorf("expected %d created by %q, got %d", count, role, counts[role])
+		}
+	}
+}
+
+func TestGetApplicationsMissingReferences(t *testing.T) {
+	stub := newMockStub()
+	for _, id := range []string{"LA-1", "LA-2"} {
+		loanApplication := testApplication(id)
+		loanApplication.SalesContractID = "CONTRACT-" + id
+		seedApplication(t, stub, loanApplication)
+		for _, reference := range []string{loanApplication.PropertyID, loanApplication.LandID, loanApplication.PermitID, loanApplication.SalesContractID} {
+			stub.PutState(reference, []byte(`{}`))
+		}
+	}
+	stub.DelState("LAND-LA-2")
+
+	var results []danglingReferences
+	err := json.Unmarshal(stub.mustQuery(t, "GetApplicationsMissingReferences"), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != "LA-2" || len(results[0].Missing) != 1 || results[0].Missing[0] != "LandID LAND-LA-2" {
+		t.Fatalf("expected only LA-2 to report its deleted land record, got %+v", results)
+	}
+}
+
+func TestGetLoanApplicationsByDecisionCode(t *testing.T) {
+	stub := newMockStub()
+	for id, code := range map[string]string{"LA-1": "DTI_TOO_HIGH", "LA-2": "INCOMPLETE_DOCS", "LA-3": "DTI_TOO_HIGH"} {
+		seedUnderReview(t, stub, testApplication(id))
+		stub.as("rev1", roleReviewer).mustInvoke(t, "RejectLoanApplication", id, code, "declined")
+	}
+	seedUnderReview(t, stub, testApplication("LA-4"))
+
+	stub.as("admin", roleAdmin)
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByDecisionCode", "DTI_TOO_HIGH")), "LA-1", "LA-3")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByDecisionCode", "LTV_EXCEEDED")))
+	if _, err := stub.query("GetLoanApplicationsByDecisionCode", "NOT_A_CODE"); err == nil {
+		t.Fatal("expected a decision code outside the taxonomy to be rejected")
+	}
+}
+
+func TestGetApplicationsSubmittedPerDay(t *testing.T) {
+	stub := newMockStub()
+	start := stub.now
+	for i, days := range []int{-1, 0, 0, 1, 3} {
+		stub.now = start.AddDate(0, 0, days)
+		createApplication(t, stub, testApplication("LA-"+strconv.Itoa(i+1)))
+	}
+
+	var counts map[string]int
+	err := json.Unmarshal(stub.mustQuery(t, "GetApplicationsSubmittedPerDay", "2024-03-15", "2024-03-17"), &counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"2024-03-15": 2, "2024-03-16": 1, "2024-03-17": 0}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, counts)
+	}
+	for day, count := range expected {
+		if counts[day] != count {
+			t.Errorf("expected %d submissions on %s, got %d", count, day, counts[day])
+		}
+	}
+	for _, dates := range [][]string{{"2024-03-17", "2024-03-15"}, {"15/03/2024", "2024-03-17"}, {"2023-01-01", "2024-03-17"}} {
+		if _, err := stub.query("GetApplicationsSubmittedPerDay", dates...); err == nil {
+			t.Errorf("expected range %v to be rejected", dates)
+		}
+	}
+}
+
+func TestGetApprovalTurnaroundMetrics(t *testing.T) {
+	stub := newMockStub()
+
+	var metrics turnaroundMetrics
+	err := json.Unmarshal(stub.mustQuery(t, "GetApprovalTurnaroundMetrics"), &metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics != (turnaroundMetrics{}) {
+		t.Fatalf("expected zero metrics with no decisions, got %+v", metrics)
+	}
+
+	created := stub.now.Add(-72 * time.Hour)
+	for id, decision := range map[string]StatusChange{
+		"LA-1": {Status: statusApproved, Date: created.Add(10 * time.Hour).Format(time.RFC3339)},
+		"LA-2": {Status: statusApproved, Date: created.Add(20 * time.Hour).Format(time.RFC3339)},
+		"LA-3": {Status: statusRejected, Date: created.Add(36 * time.Hour).Format(time.RFC3339)},
+		"LA-4": {Status: statusUnderReview, Date: created.Add(time.Hour).Format(time.RFC3339)},
+	} {
+		loanApplication := testApplication(id)
+		loanApplication.Status = decision.Status
+		loanApplication.CreatedDate = created.Format(time.RFC3339)
+		loanApplication.StatusHistory = []StatusChange{{Status: statusSubmitted, Date: loanApplication.CreatedDate}, decision}
+		seedApplication(t, stub, loanApplication)
+	}
+
+	err = json.Unmarshal(stub.mustQuery(t, "GetApprovalTurnaroundMetrics"), &metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.Decided != 3 || metrics.AverageHours != 22 || metrics.MinHours != 10 || metrics.MaxHours != 36 {
+		t.Fatalf("expected 3 decisions averaging 22 hours between 10 and 36, got %+v", metrics)
+	}
+}
+
+func TestGetLoanApplicationsByFairMarketValueRange(t *testing.T) {
+	stub := newMockStub()
+	for id, value := range map[string]int64{"LA-1": 0, "LA-2": 200000, "LA-3": 400000, "LA-4": 600000} {
+		loanApplication := testApplication(id)
+		loanApplication.FairMarketValue = value
+		seedApplication(t, stub, loanApplication)
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByFairMarketValueRange", "200000", "400000")), "LA-2", "LA-3")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByFairMarketValueRange", "0", "300000")), "LA-2")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByFairMarketValueRange", "0", "300000", "true")), "LA-1", "LA-2")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByFairMarketValueRange", "300000", "600000", "true")), "LA-1", "LA-3", "LA-4")
+	for _, values := range [][]string{{"-1", "100"}, {"500", "100"}, {"0", "100", "maybe"}} {
+		if _, err := stub.query("GetLoanApplicationsByFairMarketValueRange", values...); err == nil {
+			t.Errorf("expected range %v to be rejected", values)
+		}
+	}
+}
+
+func TestSearchLoanApplicationsMatchesPartialNames(t *testing.T) {
+	stub := newMockStub()
+	for id, name := range map[string][2]string{"LA-1": {"Jane", "Smithson"}, "LA-2": {"Ada", "Blacksmith"}, "LA-3": {"Smita", "Rao"}, "LA-4": {"John", "Doe"}} {
+		loanApplication := testApplication(id)
+		loanApplication.PersonalInfo.Firstname = name[0]
+		loanApplication.PersonalInfo.Lastname = name[1]
+		seedApplication(t, stub, loanApplication)
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "SearchLoanApplications", "SMIT")), "LA-1", "LA-2", "LA-3")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "SearchLoanApplications", " smith ")), "LA-1", "LA-2")
+	assertIDs(t, recordIDs(t, stub.as("rev1", roleReviewer).mustQuery(t, "SearchLoanApplications", "oh")), "LA-4")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "SearchLoanApplications", "zz")))
+	if _, err := stub.query("SearchLoanApplications", " "); err == nil {
+		t.Fatal("expected empty search text to be rejected")
+	}
+	if _, err := stub.as("app1", roleApplicant).query("SearchLoanApplications", "smith"); err == nil {
+		t.Fatal("expected applicants to be denied the search")
+	}
+}
+
+func TestGetTerminalApplications(t *testing.T) {
+	stub := newMockStub()
+	terminal := []string{}
+	for status := range validStatuses {
+		loanApplication := testApplication("LA-" + status)
+		loanApplication.Status = status
+		seedApplication(t, stub, loanApplication)
+		if terminalStatuses[status] {
+			terminal = append(terminal, loanApplication.ID)
+		}
+	}
+
+	assertSameIDs(t, recordIDs(t, stub.mustQuery(t, "GetTerminalApplications")),
+		"LA-"+statusDisbursed, "LA-"+statusRejected, "LA-"+statusArchived, "LA-"+statusExpired, "LA-"+statusMerged, "LA-"+statusApprovalExpired)
+	assertSameIDs(t, terminal, recordIDs(t, stub.mustQuery(t, "GetTerminalApplications"))...)
+}
+
+func TestQueryLoanApplicationsCombinesFilters(t *testing.T) {
+	stub := newMockStub()
+	seeds := []struct {
+		id, status, buyer, reviewer string
+		amount                      int64
+	}{
+		{"LA-1", statusSubmitted, "B1", "", 100000},
+		{"LA-2", statusUnderReview, "B1", "rev1", 200000},
+		{"LA-3", statusUnderReview, "B2", "rev1", 300000},
+		{"LA-4", statusUnderReview, "B2", "rev2", 400000},
+		{"LA-5", statusApproved, "B1", "rev2", 500000},
+	}
+	for _, seed := range seeds {
+		loanApplication := testApplication(seed.id)
+		loanApplication.Status = seed.status
+		loanApplication.BuyerID = seed.buyer
+		loanApplication.ReviewerID = seed.reviewer
+		loanApplication.RequestedAmount = seed.amount
+		seedApplication(t, stub, loanApplication)
+	}
+
+	cases := map[string][]string{
+		`{}`:                       {"LA-1", "LA-2", "LA-3", "LA-4", "LA-5"},
+		`{"status":"UnderReview"}`: {"LA-2", "LA-3", "LA-4"},
+		`{"status":"UnderReview","buyerId":"B2"}`:      {"LA-3", "LA-4"},
+		`{"reviewerId":"rev1","minAmount":250000}`:     {"LA-3"},
+		`{"buyerId":"B1","maxAmount":200000}`:          {"LA-1", "LA-2"},
+		`{"minAmount":200000,"maxAmount":400000}`:      {"LA-2", "LA-3", "LA-4"},
+		`{"status":"Approved","reviewerId":"rev1"}`:    {},
+		`{"minAmount":0,"buyerId":"B1","maxAmount":0}`: {},
+	}
+	for filter, expected := range cases {
+		assertIDs(t, recordIDs(t, stub.mustQuery(t, "QueryLoanApplications", filter)), expected...)
+	}
+	for _, filter := range []string{`{"state":"Approved"}`, `{"status":"Pending"}`, `{"minAmount":5,"maxAmount":1}`, `not json`} {
+		if _, err := stub.query("QueryLoanApplications", filter); err == nil {
+			t.Errorf("expected filter %s to be rejected", filter)
+		}
+	}
+}
+
+func TestGetApplicationsByTermRange(t *testing.T) {
+	stub := newMockStub()
+	for id, term := range map[string]int{"LA-1": 12, "LA-2": 60, "LA-3": 120, "LA-4": 240, "LA-5": 360} {
+		loanApplication := testApplication(id)
+		loanApplication.TermMonths = term
+		seedApplication(t, stub, loanApplication)
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByTermRange", "60", "240")), "LA-2", "LA-3", "LA-4")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByTermRange", "1", "12")), "LA-1")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByTermRange", "360", "360")), "LA-5")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByTermRange", "13", "59")))
+	for _, terms := range [][]string{{"0", "12"}, {"-12", "12"}, {"240", "120"}, {"twelve", "24"}} {
+		if _, err := stub.query("GetApplicationsByTermRange", terms...); err == nil {
+			t.Errorf("expected range %v to be rejected", terms)
+		}
+	}
+}
+
+func TestGetApplicationsByInterestRateRange(t *testing.T) {
+	stub := newMockStub()
+	for id, rateBps := range map[string]int{"LA-1": 0, "LA-2": 350, "LA-3": 525, "LA-4": 600, "LA-5": 875} {
+		loanApplication := testApplication(id)
+		loanApplication.InterestRateBps = rateBps
+		seedApplication(t, stub, loanApplication)
+	}
+
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByInterestRateRange", "3.5", "6")), "LA-2", "LA-3", "LA-4")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByInterestRateRange", "5.26", "10")), "LA-4", "LA-5")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByInterestRateRange", "0", "0")), "LA-1")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetApplicationsByInterestRateRange", "9", "12.5")))
+	for _, rates := range [][]string{{"-1", "5"}, {"6", "5"}, {"low", "5"}} {
+		if _, err := stub.query("GetApplicationsByInterestRateRange", rates...); err == nil {
+			t.Errorf("expected range %v to be rejected", rates)
+		}
+	}
+}
+
+func TestGetApplicationStateCountsOverTime(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	start := stub.now
+	createApplication(t, stub, testApplication("LA-1"))
+	createApplication(t, stub, testApplication("LA-2"))
+	stub.now = start.AddDate(0, 0, 1)
+	stub.mustInvoke(t, "AssignReviewer", "LA-1", "rev1")
+	stub.now = start.AddDate(0, 0, 2)
+	stub.as("rev1", roleReviewer).mustInvoke(t, "ApproveLoanApplication", "LA-1", "", "", "n1")
+	createApplication(t, stub, testApplication("LA-3"))
+
+	var counts map[string]map[string]int
+	err := json.Unmarshal(stub.as("admin", roleAdmin).mustQuery(t, "GetApplicationStateCountsOverTime", "2024-03-14", "2024-03-17"), &counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]int{
+		"2024-03-14": {},
+		"2024-03-15": {statusSubmitted: 2},
+		"2024-03-16": {statusSubmitted: 1, statusUnderReview: 1},
+		"2024-03-17": {statusSubmitted: 2, statusApproved: 1},
+	}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, counts)
+	}
+	for day, dayCounts := range expected {
+		if len(counts[day]) != len(dayCounts) {
+			t.Fatalf("expected %v on %s, got %v", dayCounts, day, counts[day])
+		}
+		for status, count := range dayCounts {
+			if counts[day][status] != count {
+				t.Errorf("expected %d %s on %s, got %d", count, status, day, counts[day][status])
+			}
+		}
+	}
+	for _, dates := range [][]string{{"2024-03-17", "2024-03-14"}, {"2024-3-14", "2024-03-17"}, {"2023-01-01", "2024-03-17"}} {
+		if _, err := stub.query("GetApplicationStateCountsOverTime", dates...); err == nil {
+			t.Errorf("expected range %v to be rejected", dates)
+		}
+	}
+}
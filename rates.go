@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// legacyCreditTierRatesKey Config key of the credit tier table from when rates were stored as percentages
+const legacyCreditTierRatesKey = "creditTierRates"
+
+// maxRateBps Upper bound of a rate in basis points, 1000%
+const maxRateBps = 100000
+
+// rateStepBps Precision configured rates are rounded to, defaulting to a single basis point
+func rateStepBps(params LoanParameters) int {
+	if params.InterestRateStepBps <= 0 {
+		return 1
+	}
+	return params.InterestRateStepBps
+}
+
+// parseRateBps Convert a decimal percentage such as "5.25" into basis points, rounding to the configured
+// step under the rounding policy. The decimal is parsed exactly so no float enters stored state.
+func parseRateBps(s string, params LoanParameters) (int, error) {
+	percent, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || percent.Sign() < 0 {
+		return 0, errors.New("Interest rate '" + s + "' must be a non-negative percentage")
+	}
+
+	step := int64(rateStepBps(params))
+	steps := new(big.Rat).Mul(percent, big.NewRat(100, step))
+	quo, rem := new(big.Int).QuoRem(steps.Num(), steps.Denom(), new(big.Int))
+	half := new(big.Int).Lsh(rem, 1).Cmp(steps.Denom())
+	switch params.RoundingPolicy {
+	case roundingFloor:
+	case roundingBankers:
+		if half > 0 || (half == 0 && quo.Bit(0) == 1) {
+			quo.Add(quo, big.NewInt(1))
+		}
+	default:
+		if half >= 0 {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+
+	if !quo.IsInt64() || quo.Int64()*step > maxRateBps {
+		return 0, errors.New("Interest rate '" + s + "' exceeds the maximum of " + formatRateBps(maxRateBps) + "%")
+	}
+	return int(quo.Int64() * step), nil
+}
+
+// formatRateBps Render basis points as a percentage with two decimals
+func formatRateBps(bps int) string {
+	return strconv.FormatFloat(bpsToPercent(bps), 'f', 2, 64)
+}
+
+// bpsToPercent Basis points as a percentage, for responses only
+func bpsToPercent(bps int) float64 {
+	return float64(bps) / 100
+}
+
+// interestRateMigration Outcome of MigrateInterestRates
+type interestRateMigration struct {
+	CreditTierRates  bool     `json:"creditTierRates"`
+	RateBounds       bool     `json:"rateBounds"`
+	LoanApplications int      `json:"loanApplications"`
+	Locked           []string `json:"locked"`
+}
+
+// legacyRateBounds Interest rate bounds of loan parameters saved as percentages
+type legacyRateBounds struct {
+	MinInterestRate json.Number `json:"minInterestRate"`
+	MaxInterestRate json.Number `json:"maxInterestRate"`
+}
+
+// legacyRate Interest rate of a loan application saved as a percentage
+type legacyRate struct {
+	ID           string      `json:"id"`
+	InterestRate json.Number `json:"interestRate"`
+}
+
+// MigrateInterestRates Convert the credit tier table, loan parameter rate bounds and application interest rates
+// saved as percentages into basis points under the current rounding parameters. Records already converted are
+// left alone and locked applications are skipped and reported, so the migration can be rerun once they are unlocked.
+func MigrateInterestRates(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering MigrateInterestRates")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	result := interestRateMigration{Locked: []string{}}
+
+	var legacyRates map[string]json.Number
+	found, err := getConfig(stub, legacyCreditTierRatesKey, &legacyRates)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		rates := map[string]int{}
+		for tier, rate := range legacyRates {
+			rates[tier], err = parseRateBps(rate.String(), params)
+			if err != nil {
+				return nil, errors.New("Credit tier " + tier + ": " + err.Error())
+			}
+		}
+		err = putConfig(stub, creditTierRatesKey, rates)
+		if err != nil {
+			return nil, err
+		}
+		err = stub.DelState(legacyCreditTierRatesKey)
+		if err != nil {
+			logger.Error("Could not delete config "+legacyCreditTierRatesKey, err)
+			return nil, err
+		}
+		result.CreditTierRates = true
+	}
+
+	var bounds legacyRateBounds
+	_, err = getConfig(stub, loanParametersKey, &bounds)
+	if err != nil {
+		return nil, err
+	}
+	if bounds.MinInterestRate != "" || bounds.MaxInterestRate != "" {
+		if bounds.MinInterestRate != "" {
+			params.MinInterestRateBps, err = parseRateBps(bounds.MinInterestRate.String(), params)
+			if err != nil {
+				return nil, errors.New("Minimum interest rate: " + err.Error())
+			}
+		}
+		if bounds.MaxInterestRate != "" {
+			params.MaxInterestRateBps, err = parseRateBps(bounds.MaxInterestRate.String(), params)
+			if err != nil {
+				return nil, errors.New("Maximum interest rate: " + err.Error())
+			}
+		}
+		err = putConfig(stub, loanParametersKey, &params)
+		if err != nil {
+			return nil, err
+		}
+		result.RateBounds = true
+	}
+
+	iter, err := stub.RangeQueryState(loanKeyPrefix, loanKeyEnd)
+	if err != nil {
+		logger.Error("Could not start range query over loan applications", err)
+		return nil, err
+	}
+	defer iter.Close()
+
+	legacy := []legacyRate{}
+	for iter.HasNext() {
+		key, laBytes, err := iter.Next()
+		if err != nil {
+			logger.Error("Could not read next loan application from range query", err)
+			return nil, err
+		}
+		var rate legacyRate
+		err = json.Unmarshal(laBytes, &rate)
+		if err != nil {
+			logger.Error("Could not unmarshal loan application at key "+key, err)
+			return nil, err
+		}
+		if rate.InterestRate != "" {
+			legacy = append(legacy, rate)
+		}
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	for _, rate := range legacy {
+		loanApplication, err := getLoanApplication(stub, rate.ID)
+		if err != nil {
+			return nil, err
+		}
+		if loanApplication.Locked {
+			result.Locked = append(result.Locked, rate.ID)
+			continue
+		}
+		loanApplication.InterestRateBps, err = parseRateBps(rate.InterestRate.String(), params)
+		if err != nil {
+			return nil, errors.New("Loan application " + rate.ID + ": " + err.Error())
+		}
+		// Marshalling the current struct drops the legacy interestRate field; the modification date is kept
+		// as the terms themselves have not changed
+		err = putLoanApplication(stub, &loanApplication, now)
+		if err != nil {
+			return nil, err
+		}
+		result.LoanApplications++
+	}
+
+	logger.Info("Migrated interest rates of " + strconv.Itoa(result.LoanApplications) + " loan applications to basis points")
+	return json.Marshal(result)
+}
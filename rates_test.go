@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// assertNoFloats Fail if any number in a stored JSON value has a fraction or exponent
+func assertNoFloats(t *testing.T, key string, stored []byte) {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(stored))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		case json.Number:
+			if strings.ContainsAny(v.String(), ".eE") {
+				t.Fatalf("expected only integers stored at %s, found %s in %s", key, v, stored)
+			}
+		}
+	}
+	walk(value)
+}
+
+func TestInterestRateStoredAsBasisPoints(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5.25,"B":7.125}`)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+	stub.as("rev1", roleReviewer).mustInvoke(t, "ApproveLoanApplication", "LA-1", "", "", "n1")
+
+	assertNoFloats(t, creditTierRatesKey, stub.state[creditTierRatesKey])
+	assertNoFloats(t, loanKey("LA-1"), stub.state[loanKey("LA-1")])
+	var rates map[string]int
+	if err := json.Unmarshal(stub.state[creditTierRatesKey], &rates); err != nil {
+		t.Fatal(err)
+	}
+	if rates["A"] != 525 || rates["B"] != 713 {
+		t.Fatalf("expected tier rates of 525 and 713 bps, got %v", rates)
+	}
+	if bytes.Contains(stub.state[loanKey("LA-1")], []byte(`"interestRate"`)) {
+		t.Fatal("expected no percentage rate field stored")
+	}
+
+	// 300000 over 360 months at 5.25% is 1656.61 a month
+	var quote paymentQuote
+	if err := json.Unmarshal(stub.mustQuery(t, "CalculateMonthlyPayment", "LA-1"), &quote); err != nil {
+		t.Fatal(err)
+	}
+	if quote.InterestRateBps != 525 || quote.InterestRate != 5.25 || quote.MonthlyPayment != 1657 {
+		t.Fatalf("expected 1657 a month at 525 bps, got %+v", quote)
+	}
+	if interest := monthlyInterest(300000, 525, roundingHalfUp); interest != 1313 {
+		t.Fatalf("expected 1312.50 of first month interest rounded to 1313, got %d", interest)
+	}
+}
+
+func TestParseRateBps(t *testing.T) {
+	cases := []struct {
+		input    string
+		step     int
+		expected int
+	}{
+		{"5.25", 0, 525},
+		{"0", 0, 0},
+		{" 7 ", 0, 700},
+		{"0.1", 0, 10},
+		{"5.30", 25, 525},
+		{"5.38", 25, 550},
+	}
+	for _, c := range cases {
+		rateBps, err := parseRateBps(c.input, LoanParameters{InterestRateStepBps: c.step})
+		if err != nil || rateBps != c.expected {
+			t.Errorf("expected %q at step %d to be %d bps, got %d, %v", c.input, c.step, c.expected, rateBps, err)
+		}
+	}
+	for _, input := range []string{"", "-1", "five", "1000.01"} {
+		if _, err := parseRateBps(input, LoanParameters{}); err == nil {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestMigrateInterestRates(t *testing.T) {
+	stub := newMockStub()
+	stub.state[legacyCreditTierRatesKey] = []byte(`{"A":5.25,"B":6}`)
+	stub.state[loanParametersKey] = []byte(`{"minInterestRate":2.5,"maxInterestRate":12}`)
+	for _, id := range []string{"LA-1", "LA-2"} {
+		seedApplication(t, stub, testApplication(id))
+	}
+	locked := testApplication("LA-3")
+	locked.Locked = true
+	seedApplication(t, stub, locked)
+	for _, id := range []string{"LA-1", "LA-3"} {
+		stub.state[loanKey(id)] = bytes.Replace(stub.state[loanKey(id)], []byte(`"interestRateBps":0`), []byte(`"interestRate":4.75`), 1)
+	}
+
+	var result interestRateMigration
+	if err := json.Unmarshal(stub.mustInvoke(t, "MigrateInterestRates"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.CreditTierRates || !result.RateBounds || result.LoanApplications != 1 || len(result.Locked) != 1 || result.Locked[0] != "LA-3" {
+		t.Fatalf("expected tier rates, bounds and LA-1 migrated with LA-3 locked, got %+v", result)
+	}
+	if _, ok := stub.state[legacyCreditTierRatesKey]; ok {
+		t.Fatal("expected the legacy tier table to be removed")
+	}
+	for _, key := range []string{creditTierRatesKey, loanParametersKey, loanKey("LA-1")} {
+		assertNoFloats(t, key, stub.state[key])
+	}
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.MinInterestRateBps != 250 || params.MaxInterestRateBps != 1200 {
+		t.Fatalf("expected bounds of 250 and 1200 bps, got %d and %d", params.MinInterestRateBps, params.MaxInterestRateBps)
+	}
+	if rateBps := storedApplication(t, stub, "LA-1").InterestRateBps; rateBps != 475 {
+		t.Fatalf("expected LA-1 at 475 bps, got %d", rateBps)
+	}
+
+	// Rerunning converts nothing already migrated
+	if err := json.Unmarshal(stub.mustInvoke(t, "MigrateInterestRates"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.CreditTierRates || result.LoanApplications != 0 {
+		t.Fatalf("expected a rerun to convert nothing new, got %+v", result)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// maskedFieldsKey Ledger key of the admin-managed list of fields masked by redaction
+const maskedFieldsKey = "maskedFields"
+
+// defaultMaskedFields Fields masked until an admin sets the list
+var defaultMaskedFields = []string{
+	"personalInfo.firstname",
+	"personalInfo.lastname",
+	"personalInfo.DOB",
+	"personalInfo.email",
+	"personalInfo.mobile",
+}
+
+// maskValue Keep the first character of a value and mask the rest
+func maskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	runes := []rune(value)
+	return string(runes[0]) + "***"
+}
+
+// getMaskedFields Load the dotted JSON paths masked by redaction
+func getMaskedFields(stub shim.ChaincodeStubInterface) ([]string, error) {
+	fields := defaultMaskedFields
+	_, err := getConfig(stub, maskedFieldsKey, &fields)
+	return fields, err
+}
+
+// maskPath Mask the value at a dotted path, partially masking strings and removing anything else
+func maskPath(object map[string]interface{}, path string) {
+	parts := strings.SplitN(path, ".", 2)
+	value, ok := object[parts[0]]
+	if !ok {
+		return
+	}
+	if len(parts) == 2 {
+		if child, ok := value.(map[string]interface{}); ok {
+			maskPath(child, parts[1])
+		}
+		return
+	}
+	if s, ok := value.(string); ok {
+		object[parts[0]] = maskValue(s)
+	} else if value != nil {
+		object[parts[0]] = nil
+	}
+}
+
+// redactLoanApplication Marshal an application with the configured fields masked, for callers not
+// entitled to see them
+func redactLoanApplication(stub shim.ChaincodeStubInterface, loanApplication LoanApplication) ([]byte, error) {
+	fields, err := getMaskedFields(stub)
+	if err != nil {
+		return nil, err
+	}
+	return maskLoanApplication(loanApplication, fields)
+}
+
+// maskLoanApplication Marshal an application with the given dotted field paths masked
+func maskLoanApplication(loanApplication LoanApplication, fields []string) ([]byte, error) {
+	laBytes, err := json.Marshal(&loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	err = json.Unmarshal(laBytes, &object)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		maskPath(object, field)
+	}
+	return json.Marshal(object)
+}
+
+// loanApplicationViewer Build a function rendering applications as the caller may see them, checking the
+// caller's role and loading the consent rules and masked fields once for use across many records. Bank staff
+// see full records of applicants holding every required consent and masked records otherwise; everyone else
+// gets masked records with only applicant-visible notes.
+func loanApplicationViewer(stub shim.ChaincodeStubInterface) (func(LoanApplication) (json.RawMessage, error), error) {
+	staff := checkRole(stub, "view unredacted loan applications", roleAdmin, roleReviewer) == nil
+	consented, err := piiConsentChecker(stub)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := getMaskedFields(stub)
+	if err != nil {
+		return nil, err
+	}
+	return func(loanApplication LoanApplication) (json.RawMessage, error) {
+		if staff && consented(loanApplication) {
+			return json.Marshal(&loanApplication)
+		}
+		if !staff {
+			loanApplication.Notes = filterNotes(loanApplication.Notes, false)
+		}
+		return maskLoanApplication(loanApplication, fields)
+	}, nil
+}
+
+// viewLoanApplications Render applications as the caller may see them, merging in any computed fields
+// given for the application at the same position
+func viewLoanApplications(stub shim.ChaincodeStubInterface, loanApplications []LoanApplication, computed []map[string]interface{}) ([]json.RawMessage, error) {
+	view, err := loanApplicationViewer(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	views := []json.RawMessage{}
+	for i, loanApplication := range loanApplications {
+		record, err := view(loanApplication)
+		if err != nil {
+			logger.Error("Could not render loan application "+loanApplication.ID, err)
+			return nil, err
+		}
+		if computed != nil && len(computed[i]) > 0 {
+			var object map[string]json.RawMessage
+			err = json.Unmarshal(record, &object)
+			if err != nil {
+				return nil, err
+			}
+			for name, value := range computed[i] {
+				object[name], err = json.Marshal(value)
+				if err != nil {
+					return nil, err
+				}
+			}
+			record, err = json.Marshal(object)
+			if err != nil {
+				return nil, err
+			}
+		}
+		views = append(views, record)
+	}
+	return views, nil
+}
+
+// marshalLoanApplications Marshal a list of applications as the caller may see them. Every query returning
+// many records goes through here, so redaction cannot be bypassed by listing instead of getting.
+func marshalLoanApplications(stub shim.ChaincodeStubInterface, loanApplications []LoanApplication) ([]byte, error) {
+	views, err := viewLoanApplications(stub, loanApplications, nil)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := json.Marshal(views)
+	if err != nil {
+		logger.Error("Could not marshal loan applications", err)
+		return nil, err
+	}
+	return bytes, nil
+}
+
+// SetMaskedFields Replace the masked fields with the JSON array of dotted field paths in args[0]
+func SetMaskedFields(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetMaskedFields")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected masked fields argument")
+	}
+
+	var fields []string
+	err := json.Unmarshal([]byte(args[0]), &fields)
+	if err != nil {
+		logger.Error("Could not unmarshal masked fields", err)
+		return nil, errors.New("Invalid masked fields: " + err.Error())
+	}
+
+	known, err := loanApplicationFields(LoanApplication{})
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if _, ok := known[field]; ok {
+			continue
+		}
+		// Whole objects such as personalInfo may be masked as well as single fields
+		isObject := false
+		for name := range known {
+			if strings.HasPrefix(name, field+".") {
+				isObject = true
+				break
+			}
+		}
+		if !isObject {
+			return nil, errors.New("Unknown loan application field '" + field + "'")
+		}
+	}
+
+	err = putConfig(stub, maskedFieldsKey, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully saved masked fields")
+	return nil, nil
+}
+
+// GetMaskedFields Get the fields currently masked by redaction
+func GetMaskedFields(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetMaskedFields")
+
+	fields, err := getMaskedFields(stub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
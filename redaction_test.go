@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetLoanApplicationRawDeniesNonAuditors(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	for _, role := range []string{roleAdmin, roleReviewer, roleSeniorReviewer, roleApplicant} {
+		_, err := stub.as("someone", role).query("GetLoanApplicationRaw", "LA-1")
+		if _, ok := err.(*PermissionError); !ok {
+			t.Errorf("expected role %s to be denied, got %v", role, err)
+		}
+	}
+}
+
+func TestGetLoanApplicationRawReturnsUnredactedRecord(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	var masked LoanApplication
+	err := json.Unmarshal(stub.as("applicant", roleApplicant).mustQuery(t, "GetLoanApplication", "LA-1"), &masked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if masked.PersonalInfo.Lastname != "D***" {
+		t.Fatalf("expected GetLoanApplication to mask the last name, got %s", masked.PersonalInfo.Lastname)
+	}
+
+	var raw LoanApplication
+	err = json.Unmarshal(stub.as("auditor", roleAuditor).mustQuery(t, "GetLoanApplicationRaw", "LA-1"), &raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.PersonalInfo.Lastname != "Doe-LA-1" || raw.PersonalInfo.Email != "LA-1@example.com" {
+		t.Fatalf("expected the auditor to see unmasked personal information, got %+v", raw.PersonalInfo)
+	}
+}
+
+func TestSetMaskedFieldsChangesRedaction(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+	view := func() map[string]interface{} {
+		var record map[string]interface{}
+		err := json.Unmarshal(stub.as("applicant", roleApplicant).mustQuery(t, "GetLoanApplication", "LA-1"), &record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stub.as("admin", roleAdmin)
+		return record
+	}
+
+	stub.mustInvoke(t, "SetMaskedFields", `["personalInfo.email","financialInfo.monthlySalary","BuyerID"]`)
+	record := view()
+	personalInfo := record["personalInfo"].(map[string]interface{})
+	if personalInfo["email"] != "L***" || personalInfo["lastname"] != "Doe-LA-1" {
+		t.Fatalf("expected only the email masked, got %v", personalInfo)
+	}
+	if record["financialInfo"].(map[string]interface{})["monthlySalary"] != nil || record["BuyerID"] != "B***" {
+		t.Fatalf("expected the salary removed and the buyer masked, got %v", record)
+	}
+
+	stub.mustInvoke(t, "SetMaskedFields", `["personalInfo"]`)
+	record = view()
+	if record["personalInfo"] != nil || record["BuyerID"] != "BUYER-LA-1" {
+		t.Fatalf("expected the whole personal information removed and the buyer shown, got %v", record)
+	}
+	if _, err := stub.invoke("SetMaskedFields", `["personalInfo.nickname"]`); err == nil {
+		t.Fatal("expected an unknown field to be rejected")
+	}
+}
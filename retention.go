@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const deletionLogIndexName = "deletion~id"
+
+// DeletionLogEntry schema
+type DeletionLogEntry struct {
+	ID        string `json:"id"`
+	DeletedAt string `json:"deletedAt"`
+	TxID      string `json:"txId"`
+	Reason    string `json:"reason"`
+}
+
+// deleteLoanApplication Remove an application, its index entries and its recorded versions, recording the deletion
+func deleteLoanApplication(stub shim.ChaincodeStubInterface, loanApplication LoanApplication, now time.Time, reason string) error {
+	err := stub.DelState(loanKey(loanApplication.ID))
+	if err != nil {
+		logger.Error("Could not delete loan application "+loanApplication.ID, err)
+		return err
+	}
+	err = removeLoanIndexes(stub, loanApplication)
+	if err != nil {
+		return err
+	}
+	// Snapshots hold the full record, personal information included
+	keys, err := getKeysByPartialCompositeKey(stub, historyIndexName, []string{loanApplication.ID})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = stub.DelState(key)
+		if err != nil {
+			logger.Error("Could not delete history entry for "+loanApplication.ID, err)
+			return err
+		}
+	}
+
+	entry := DeletionLogEntry{
+		ID:        loanApplication.ID,
+		DeletedAt: now.Format(time.RFC3339),
+		TxID:      stub.GetTxID(),
+		Reason:    reason,
+	}
+	entryBytes, err := marshalState(&entry)
+	if err != nil {
+		return err
+	}
+	key, err := createCompositeKey(deletionLogIndexName, []string{loanApplication.ID})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, entryBytes)
+}
+
+// PurgeArchivedApplications Delete archived applications last modified before the retention window
+func PurgeArchivedApplications(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering PurgeArchivedApplications")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	if params.ArchiveRetentionDays <= 0 {
+		return nil, errors.New("Archive retention window is not configured")
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := now.AddDate(0, 0, -params.ArchiveRetentionDays)
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	purged := 0
+	for _, loanApplication := range loanApplications {
+		if loanApplication.Status != statusArchived || loanApplication.Locked {
+			continue
+		}
+		modified, err := time.Parse(time.RFC3339, loanApplication.LastModifiedDate)
+		if err != nil {
+			logger.Warning("Skipping archived loan application " + loanApplication.ID + " with unparseable last modified date")
+			continue
+		}
+		if !modified.Before(cutoff) {
+			continue
+		}
+		err = deleteLoanApplication(stub, loanApplication, now, "retention purge")
+		if err != nil {
+			return nil, err
+		}
+		purged++
+	}
+
+	err = sendSummaryEvent(stub, "archivedApplicationsPurged", "Purged "+strconv.Itoa(purged)+" archived loan applications", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Purged " + strconv.Itoa(purged) + " archived loan applications")
+	return []byte(strconv.Itoa(purged)), nil
+}
+
+// expirableStatuses Statuses of undecided applications that lapse when left idle
+var expirableStatuses = map[string]bool{
+	statusSubmitted:             true,
+	statusUnderReview:           true,
+	statusPendingSecondApproval: true,
+}
+
+// expirySweep Payload of the expirySweep event, naming the applications reminded and expired
+type expirySweep struct {
+	Reminded []string `json:"reminded"`
+	Expired  []string `json:"expired"`
+}
+
+// ExpireStaleApplications Remind idle undecided applications, expiring those still idle once the grace period has passed
+func ExpireStaleApplications(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering ExpireStaleApplications")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	if params.ApplicationExpiryDays <= 0 {
+		return nil, errors.New("Application expiry period is not configured")
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	idleCutoff := now.AddDate(0, 0, -params.ApplicationExpiryDays)
+	graceCutoff := now.AddDate(0, 0, -params.ExpiryGraceDays)
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	sweep := expirySweep{Reminded: []string{}, Expired: []string{}}
+	for _, loanApplication := range loanApplications {
+		if !expirableStatuses[loanApplication.Status] || loanApplication.Locked {
+			continue
+		}
+		modified, err := time.Parse(time.RFC3339, loanApplication.LastModifiedDate)
+		if err != nil {
+			logger.Warning("Skipping loan application " + loanApplication.ID + " with unparseable last modified date")
+			continue
+		}
+
+		// A reminder is outstanding until the application is modified after it was sent
+		if loanApplication.ReminderDate != "" && loanApplication.ReminderDate == loanApplication.LastModifiedDate {
+			if modified.After(graceCutoff) {
+				continue
+			}
+			err = setStatus(stub, &loanApplication, statusExpired, "expired after reminder grace period")
+			if err != nil {
+				return nil, err
+			}
+			err = saveLoanApplication(stub, &loanApplication)
+			if err != nil {
+				return nil, err
+			}
+			sweep.Expired = append(sweep.Expired, loanApplication.ID)
+			continue
+		}
+
+		if !modified.Before(idleCutoff) {
+			continue
+		}
+		loanApplication.RemindersSent++
+		loanApplication.ReminderDate = now.Format(time.RFC3339)
+		err = saveLoanApplication(stub, &loanApplication)
+		if err != nil {
+			return nil, err
+		}
+		sweep.Reminded = append(sweep.Reminded, loanApplication.ID)
+	}
+
+	// The shim keeps one event per transaction, so the summary carries who to remind
+	reminded, expired := strconv.Itoa(len(sweep.Reminded)), strconv.Itoa(len(sweep.Expired))
+	err = sendSummaryEvent(stub, "expirySweep", "Sent "+reminded+" reminders and expired "+expired+" loan applications", sweep)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Sent " + reminded + " expiry reminders and expired " + expired + " loan applications")
+	return []byte(expired), nil
+}
+
+// piiPurgeStatuses Terminal statuses whose personal information is purged after the retention window
+var piiPurgeStatuses = map[string]bool{
+	statusRejected: true,
+	statusExpired:  true,
+}
+
+// purgeHistoryPersonalInfo Blank the personal information held in every recorded version of an application
+func purgeHistoryPersonalInfo(stub shim.ChaincodeStubInterface, loanAppID string) error {
+	keys, err := getKeysByPartialCompositeKey(stub, historyIndexName, []string{loanAppID})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		entryBytes, err := stub.GetState(key)
+		if err != nil {
+			logger.Error("Could not fetch history entry for "+loanAppID, err)
+			return err
+		}
+		var entry historyEntry
+		err = json.Unmarshal(entryBytes, &entry)
+		if err != nil {
+			logger.Error("Could not unmarshal history entry for "+loanAppID, err)
+			return err
+		}
+		entry.Value.PersonalInfo = PersonalInfo{}
+		entry.Value.PIIPurged = true
+		entryBytes, err = marshalState(&entry)
+		if err != nil {
+			return err
+		}
+		err = stub.PutState(key, entryBytes)
+		if err != nil {
+			logger.Error("Could not save history entry for "+loanAppID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgePII Blank the personal information of rejected and expired applications last modified
+// before the PII retention window, keeping the rest of the record for reporting
+func PurgePII(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering PurgePII")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	if params.PIIRetentionDays <= 0 {
+		return nil, errors.New("PII retention window is not configured")
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := now.AddDate(0, 0, -params.PIIRetentionDays)
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	purged := 0
+	for _, loanApplication := range loanApplications {
+		if !piiPurgeStatuses[loanApplication.Status] || loanApplication.PIIPurged || loanApplication.Locked {
+			continue
+		}
+		modified, err := time.Parse(time.RFC3339, loanApplication.LastModifiedDate)
+		if err != nil {
+			logger.Warning("Skipping loan application " + loanApplication.ID + " with unparseable last modified date")
+			continue
+		}
+		if !modified.Before(cutoff) {
+			continue
+		}
+
+		// Earlier snapshots hold the same personal information, so they are purged too
+		err = purgeHistoryPersonalInfo(stub, loanApplication.ID)
+		if err != nil {
+			return nil, err
+		}
+		loanApplication.PersonalInfo = PersonalInfo{}
+		loanApplication.PIIPurged = true
+		err = saveLoanApplication(stub, &loanApplication)
+		if err != nil {
+			return nil, err
+		}
+		purged++
+	}
+
+	err = sendSummaryEvent(stub, "piiPurged", "Purged personal information from "+strconv.Itoa(purged)+" loan applications", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Purged personal information from " + strconv.Itoa(purged) + " loan applications")
+	return []byte(strconv.Itoa(purged)), nil
+}
+
+// ExpireApprovals Move approved applications with nothing yet disbursed to ApprovalExpired once their
+// approval expiry date has passed
+func ExpireApprovals(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering ExpireApprovals")
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := 0
+	for _, loanApplication := range loanApplications {
+		if loanApplication.Status != statusApproved || loanApplication.ApprovalExpiryDate == "" || loanApplication.Locked {
+			continue
+		}
+		if loanApplication.DisbursementInfo.DisbursedAmount > 0 {
+			continue
+		}
+		expiry, err := time.Parse(time.RFC3339, loanApplication.ApprovalExpiryDate)
+		if err != nil {
+			logger.Warning("Skipping loan application " + loanApplication.ID + " with unparseable approval expiry date")
+			continue
+		}
+		if !now.After(expiry) {
+			continue
+		}
+
+		err = setStatus(stub, &loanApplication, statusApprovalExpired, "approval expired undrawn")
+		if err != nil {
+			return nil, err
+		}
+		err = saveLoanApplication(stub, &loanApplication)
+		if err != nil {
+			return nil, err
+		}
+		expired++
+	}
+
+	err = sendSummaryEvent(stub, "approvalExpirySweep", "Expired "+strconv.Itoa(expired)+" undrawn approvals", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Expired " + strconv.Itoa(expired) + " undrawn approvals")
+	return []byte(strconv.Itoa(expired)), nil
+}
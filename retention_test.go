@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPurgeArchivedApplicationsRetentionWindow(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"archiveRetentionDays":30}`)
+
+	old := testApplication("LA-1")
+	old.Status = statusArchived
+	old.LastModifiedDate = stub.now.AddDate(0, 0, -31).Format(time.RFC3339)
+	seedApplication(t, stub, old)
+	recent := testApplication("LA-2")
+	recent.Status = statusArchived
+	recent.LastModifiedDate = stub.now.AddDate(0, 0, -29).Format(time.RFC3339)
+	seedApplication(t, stub, recent)
+	oldRejected := testApplication("LA-3")
+	oldRejected.Status = statusRejected
+	oldRejected.LastModifiedDate = old.LastModifiedDate
+	seedApplication(t, stub, oldRejected)
+	if entries, _ := getLoanApplicationHistory(stub, "LA-1"); len(entries) == 0 {
+		t.Fatal("expected LA-1 to have recorded versions before the purge")
+	}
+
+	if purged := string(stub.mustInvoke(t, "PurgeArchivedApplications")); purged != "1" {
+		t.Fatalf("expected 1 application purged, got %s", purged)
+	}
+	if stub.state[loanKey("LA-1")] != nil {
+		t.Fatal("expected LA-1 outside the window to be purged")
+	}
+	storedApplication(t, stub, "LA-2")
+	storedApplication(t, stub, "LA-3")
+	if keys, _ := getKeysByPartialCompositeKey(stub, buyerIndexName, []string{"BUYER-LA-1"}); len(keys) != 0 {
+		t.Fatal("expected the purged application's index entries to be removed")
+	}
+	entries, err := getLoanApplicationHistory(stub, "LA-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected the purged application's recorded versions to be removed, got %d", len(entries))
+	}
+	if entries, _ := getLoanApplicationHistory(stub, "LA-2"); len(entries) == 0 {
+		t.Fatal("expected LA-2 to keep its recorded versions")
+	}
+
+	key, _ := createCompositeKey(deletionLogIndexName, []string{"LA-1"})
+	var entry DeletionLogEntry
+	err = json.Unmarshal(stub.state[key], &entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Reason != "retention purge" || entry.TxID != stub.txID {
+		t.Fatalf("expected a retention purge log entry, got %+v", entry)
+	}
+}
+
+func TestPurgeArchivedApplicationsRequiresWindow(t *testing.T) {
+	stub := newMockStub()
+
+	if _, err := stub.invoke("PurgeArchivedApplications"); err == nil {
+		t.Fatal("expected an unconfigured retention window to be rejected")
+	}
+}
+
+// expirySweepOf The payload of the expirySweep event set by the last transaction
+func expirySweepOf(t *testing.T, stub *mockStub) expirySweep {
+	t.Helper()
+	var evt struct {
+		Type    string      `json:"type"`
+		Payload expirySweep `json:"payload"`
+	}
+	err := json.Unmarshal(stub.lastEvent(t).payload, &evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.Type != "expirySweep" {
+		t.Fatalf("expected an expirySweep event, got %s", evt.Type)
+	}
+	return evt.Payload
+}
+
+func TestExpireStaleApplicationsRemindsThenExpires(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"applicationExpiryDays":30,"expiryGraceDays":7}`)
+	idle := stub.now.AddDate(0, 0, -31).Format(time.RFC3339)
+	for _, id := range []string{"LA-1", "LA-2"} {
+		loanApplication := testApplication(id)
+		loanApplication.LastModifiedDate = idle
+		seedApplication(t, stub, loanApplication)
+	}
+	recent := testApplication("LA-3")
+	recent.LastModifiedDate = stub.now.AddDate(0, 0, -29).Format(time.RFC3339)
+	seedApplication(t, stub, recent)
+
+	if expired := string(stub.mustInvoke(t, "ExpireStaleApplications")); expired != "0" {
+		t.Fatalf("expected reminders before any expiry, got %s expired", expired)
+	}
+	sweep := expirySweepOf(t, stub)
+	assertIDs(t, sweep.Reminded, "LA-1", "LA-2")
+	assertIDs(t, sweep.Expired)
+	for id, reminders := range map[string]int{"LA-1": 1, "LA-2": 1, "LA-3": 0} {
+		if loanApplication := storedApplication(t, stub, id); loanApplication.RemindersSent != reminders || loanApplication.Status != statusSubmitted {
+			t.Fatalf("expected %s to be Submitted with %d reminders, got %+v", id, reminders, loanApplication)
+		}
+	}
+
+	// Still inside the grace period, and LA-2 is updated in response to its reminder
+	stub.now = stub.now.AddDate(0, 0, 3)
+	stub.mustInvoke(t, "AddNote", "LA-2", "applicant responded")
+	if expired := string(stub.mustInvoke(t, "ExpireStaleApplications")); expired != "0" {
+		t.Fatalf("expected nothing to expire within the grace period, got %s", expired)
+	}
+
+	stub.now = stub.now.AddDate(0, 0, 5)
+	if expired := string(stub.mustInvoke(t, "ExpireStaleApplications")); expired != "1" {
+		t.Fatalf("expected one application to expire, got %s", expired)
+	}
+	sweep = expirySweepOf(t, stub)
+	assertIDs(t, sweep.Reminded)
+	assertIDs(t, sweep.Expired, "LA-1")
+	if status := storedApplication(t, stub, "LA-1").Status; status != statusExpired {
+		t.Fatalf("expected LA-1 to expire once the grace period passed, got %s", status)
+	}
+	if status := storedApplication(t, stub, "LA-2").Status; status != statusSubmitted {
+		t.Fatalf("expected the updated LA-2 to stay Submitted, got %s", status)
+	}
+}
+
+func TestPurgePIIEligibleRecords(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"piiRetentionDays":90}`)
+	old := stub.now.AddDate(0, 0, -91).Format(time.RFC3339)
+
+	for id, status := range map[string]string{"LA-1": statusRejected, "LA-2": statusExpired} {
+		loanApplication := testApplication(id)
+		loanApplication.Status = status
+		loanApplication.LastModifiedDate = old
+		seedApplication(t, stub, loanApplication)
+	}
+
+	if purged := string(stub.mustInvoke(t, "PurgePII")); purged != "2" {
+		t.Fatalf("expected 2 applications purged, got %s", purged)
+	}
+	for _, id := range []string{"LA-1", "LA-2"} {
+		loanApplication := storedApplication(t, stub, id)
+		if !loanApplication.PIIPurged || loanApplication.PersonalInfo != (PersonalInfo{}) {
+			t.Errorf("expected %s personal information purged, got %+v", id, loanApplication.PersonalInfo)
+		}
+		if loanApplication.RequestedAmount != 300000 || loanApplication.BuyerID != "BUYER-"+id {
+			t.Errorf("expected the rest of %s to be kept", id)
+		}
+		history, err := getLoanApplicationHistory(stub, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range history {
+			if entry.Value.PersonalInfo.Lastname != "" {
+				t.Errorf("expected the history of %s to be purged too", id)
+			}
+		}
+	}
+}
+
+func TestPurgePIISkipsIneligibleRecords(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"piiRetentionDays":90}`)
+	old := stub.now.AddDate(0, 0, -91).Format(time.RFC3339)
+
+	recent := testApplication("LA-1")
+	recent.Status = statusRejected
+	recent.LastModifiedDate = stub.now.AddDate(0, 0, -89).Format(time.RFC3339)
+	seedApplication(t, stub, recent)
+	approved := testApplication("LA-2")
+	approved.Status = statusApproved
+	approved.LastModifiedDate = old
+	seedApplication(t, stub, approved)
+	locked := testApplication("LA-3")
+	locked.Status = statusRejected
+	locked.LastModifiedDate = old
+	locked.Locked = true
+	seedApplication(t, stub, locked)
+
+	if purged := string(stub.mustInvoke(t, "PurgePII")); purged != "0" {
+		t.Fatalf("expected nothing purged, got %s", purged)
+	}
+	for _, id := range []string{"LA-1", "LA-2", "LA-3"} {
+		if loanApplication := storedApplication(t, stub, id); loanApplication.PIIPurged || loanApplication.PersonalInfo.Lastname != "Doe-"+id {
+			t.Errorf("expected %s personal information to be kept", id)
+		}
+	}
+}
+
+func TestApprovalSetsExpiryDate(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	stub.mustInvoke(t, "SetLoanParameters", `{"approvalValidityDays":30}`)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+
+	stub.as("rev1", roleReviewer).mustInvoke(t, "ApproveLoanApplication", "LA-1", "", "", "n1")
+
+	expected := stub.now.AddDate(0, 0, 30).Format(time.RFC3339)
+	if expiry := storedApplication(t, stub, "LA-1").ApprovalExpiryDate; expiry != expected {
+		t.Fatalf("expected approval expiry %s, got %s", expected, expiry)
+	}
+}
+
+func TestExpireApprovals(t *testing.T) {
+	stub := newMockStub()
+	seeds := []struct {
+		id        string
+		expiry    time.Time
+		disbursed int64
+	}{
+		{"LA-1", stub.now.AddDate(0, 0, -1), 0},
+		{"LA-2", stub.now.AddDate(0, 0, 1), 0},
+		{"LA-3", stub.now.AddDate(0, 0, -1), 50000},
+		{"LA-4", stub.now, 0},
+	}
+	for _, seed := range seeds {
+		loanApplication := testApplication(seed.id)
+		loanApplication.Status = statusApproved
+		loanApplication.ApprovedAmount = 300000
+		loanApplication.ApprovalExpiryDate = seed.expiry.Format(time.RFC3339)
+		loanApplication.DisbursementInfo.DisbursedAmount = seed.disbursed
+		seedApplication(t, stub, loanApplication)
+	}
+	// Approved before expiry dates were recorded
+	seedApproved(t, stub, "LA-5", 300000)
+
+	if expired := string(stub.mustInvoke(t, "ExpireApprovals")); expired != "1" {
+		t.Fatalf("expected 1 approval expired, got %s", expired)
+	}
+	expected := map[string]string{
+		"LA-1": statusApprovalExpired,
+		"LA-2": statusApproved,
+		"LA-3": statusApproved,
+		"LA-4": statusApproved,
+		"LA-5": statusApproved,
+	}
+	for id, status := range expected {
+		if actual := storedApplication(t, stub, id).Status; actual != status {
+			t.Fatalf("expected %s to be %s, got %s", id, status, actual)
+		}
+	}
+
+	stub.now = stub.now.AddDate(0, 0, 2)
+	if expired := string(stub.mustInvoke(t, "ExpireApprovals")); expired != "2" {
+		t.Fatalf("expected 2 more approvals expired, got %s", expired)
+	}
+	if _, err := stub.as("rev1", roleReviewer).invoke("ExpireApprovals"); err == nil {
+		t.Fatal("expected reviewers to be denied")
+	}
+}
@@ -0,0 +1,612 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	reviewerRosterKey = "reviewerRoster"
+	reviewerIndexName = "reviewer~id"
+)
+
+// Reviewer schema
+type Reviewer struct {
+	ID     string `json:"id"`
+	Active bool   `json:"active"`
+	Senior bool   `json:"senior"`
+}
+
+// ReviewerRoster schema
+type ReviewerRoster struct {
+	Reviewers []Reviewer `json:"reviewers"`
+}
+
+// getReviewerRoster Load the reviewer roster, empty if none has been set
+func getReviewerRoster(stub shim.ChaincodeStubInterface) (ReviewerRoster, error) {
+	var roster ReviewerRoster
+	_, err := getConfig(stub, reviewerRosterKey, &roster)
+	return roster, err
+}
+
+// isActiveReviewer Check the roster for an active reviewer with the given ID
+func (r ReviewerRoster) isActiveReviewer(reviewerID string) bool {
+	for _, reviewer := range r.Reviewers {
+		if reviewer.ID == reviewerID {
+			return reviewer.Active
+		}
+	}
+	return false
+}
+
+// isActiveSeniorReviewer Check the roster for an active senior reviewer with the given ID
+func (r ReviewerRoster) isActiveSeniorReviewer(reviewerID string) bool {
+	for _, reviewer := range r.Reviewers {
+		if reviewer.ID == reviewerID {
+			return reviewer.Active && reviewer.Senior
+		}
+	}
+	return false
+}
+
+// SetReviewerRoster Replace the reviewer roster with the JSON in args[0]
+func SetReviewerRoster(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetReviewerRoster")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected reviewer roster argument")
+	}
+
+	var roster ReviewerRoster
+	err := json.Unmarshal([]byte(args[0]), &roster)
+	if err != nil {
+		logger.Error("Could not unmarshal reviewer roster", err)
+		return nil, errors.New("Invalid reviewer roster: " + err.Error())
+	}
+	seen := map[string]bool{}
+	for _, reviewer := range roster.Reviewers {
+		if reviewer.ID == "" {
+			return nil, errors.New("Reviewer roster contains a reviewer with no id")
+		}
+		if seen[reviewer.ID] {
+			return nil, errors.New("Reviewer " + reviewer.ID + " appears more than once in the roster")
+		}
+		seen[reviewer.ID] = true
+	}
+
+	err = putConfig(stub, reviewerRosterKey, &roster)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully saved reviewer roster")
+	return nil, nil
+}
+
+// GetReviewerRoster Get the current reviewer roster
+func GetReviewerRoster(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetReviewerRoster")
+
+	roster, err := getReviewerRoster(stub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&roster)
+}
+
+// reviewerNotification Payload telling an assigned reviewer about a new application
+type reviewerNotification struct {
+	ReviewerID    string `json:"reviewerId"`
+	ApplicationID string `json:"applicationId"`
+}
+
+// openForReview Report whether an application is still awaiting a review decision
+func openForReview(status string) bool {
+	return status == statusSubmitted || status == statusUnderReview
+}
+
+// assignReviewer Assign an application to a reviewer and keep the reviewer index in sync
+func assignReviewer(stub shim.ChaincodeStubInterface, loanAppID string, reviewerID string) error {
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return err
+	}
+	if !openForReview(loanApplication.Status) {
+		return errors.New("Loan application " + loanAppID + " is " + loanApplication.Status + " and cannot be assigned a reviewer")
+	}
+
+	if loanApplication.ReviewerID != "" {
+		err = delIndexEntry(stub, reviewerIndexName, []string{loanApplication.ReviewerID, loanAppID})
+		if err != nil {
+			logger.Error("Could not remove previous reviewer index entry", err)
+			return err
+		}
+	}
+
+	loanApplication.ReviewerID = reviewerID
+	err = setStatus(stub, &loanApplication, statusUnderReview, "assigned to "+reviewerID)
+	if err != nil {
+		return err
+	}
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return err
+	}
+
+	err = putIndexEntry(stub, reviewerIndexName, []string{reviewerID, loanAppID})
+	if err != nil {
+		logger.Error("Could not save reviewer index entry", err)
+		return err
+	}
+
+	// Carries the reviewer so an off-chain notifier can route it; the shim allows one event per transaction,
+	// so this is also the assignment event
+	return sendEvent(stub, "reviewerNotification", loanAppID+" assigned to "+reviewerID, reviewerNotification{
+		ReviewerID:    reviewerID,
+		ApplicationID: loanAppID,
+	})
+}
+
+// countOpenAssignments Count the applications a reviewer still has under review
+func countOpenAssignments(stub shim.ChaincodeStubInterface, reviewerID string) (int, error) {
+	keys, err := getKeysByPartialCompositeKey(stub, reviewerIndexName, []string{reviewerID})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, key := range keys {
+		_, attributes := splitCompositeKey(key)
+		if len(attributes) < 2 {
+			continue
+		}
+		loanApplication, err := getLoanApplication(stub, attributes[1])
+		if err != nil {
+			logger.Debug("Skipping stale reviewer index entry " + attributes[1])
+			continue
+		}
+		if loanApplication.Status == statusUnderReview && loanApplication.ReviewerID == reviewerID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// AssignReviewer Assign application args[0] to reviewer args[1]
+func AssignReviewer(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering AssignReviewer")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID and reviewer ID")
+	}
+
+	var loanAppID = args[0]
+	var reviewerID = args[1]
+
+	roster, err := getReviewerRoster(stub)
+	if err != nil {
+		return nil, err
+	}
+	if !roster.isActiveReviewer(reviewerID) {
+		return nil, errors.New("Reviewer " + reviewerID + " is not an active reviewer")
+	}
+
+	err = assignReviewer(stub, loanAppID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully assigned " + loanAppID + " to " + reviewerID)
+	return nil, nil
+}
+
+// AutoAssignReviewer Assign application args[0] to the active reviewer with the fewest open assignments
+func AutoAssignReviewer(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering AutoAssignReviewer")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var loanAppID = args[0]
+
+	roster, err := getReviewerRoster(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ties go to the reviewer listed first in the roster so every peer picks the same one
+	var chosen string
+	lowest := -1
+	for _, reviewer := range roster.Reviewers {
+		if !reviewer.Active {
+			continue
+		}
+		count, err := countOpenAssignments(stub, reviewer.ID)
+		if err != nil {
+			return nil, err
+		}
+		if lowest == -1 || count < lowest {
+			chosen = reviewer.ID
+			lowest = count
+		}
+	}
+	if chosen == "" {
+		return nil, errors.New("No active reviewers available for assignment")
+	}
+
+	err = assignReviewer(stub, loanAppID, chosen)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully auto-assigned " + loanAppID + " to " + chosen)
+	return []byte(chosen), nil
+}
+
+// GetLoanApplicationCountByReviewer Get how many applications each reviewer currently owns
+func GetLoanApplicationCountByReviewer(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationCountByReviewer")
+
+	roster, err := getReviewerRoster(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, reviewer := range roster.Reviewers {
+		counts[reviewer.ID] = 0
+	}
+
+	// Index entries outlive decisions, so they only nominate reviewers; the counts come from the open records
+	keys, err := getKeysByPartialCompositeKey(stub, reviewerIndexName, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		_, attributes := splitCompositeKey(key)
+		if len(attributes) < 2 {
+			continue
+		}
+		counts[attributes[0]] = 0
+	}
+	for reviewerID := range counts {
+		counts[reviewerID], err = countOpenAssignments(stub, reviewerID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(counts)
+}
+
+// reassignResult Outcome of ReassignAllForReviewer
+type reassignResult struct {
+	Reassigned int      `json:"reassigned"`
+	Locked     []string `json:"locked"`
+}
+
+// ReassignAllForReviewer Move every open application owned by reviewer args[0] to reviewer args[1]. Decided
+// applications keep their reviewer, and locked ones are left in place and reported.
+func ReassignAllForReviewer(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering ReassignAllForReviewer")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected old and new reviewer IDs")
+	}
+
+	var oldReviewerID = args[0]
+	var newReviewerID = args[1]
+
+	if oldReviewerID == newReviewerID {
+		return nil, errors.New("Old and new reviewer must differ")
+	}
+	roster, err := getReviewerRoster(stub)
+	if err != nil {
+		return nil, err
+	}
+	if !roster.isActiveReviewer(newReviewerID) {
+		return nil, errors.New("Reviewer " + newReviewerID + " is not an active reviewer")
+	}
+
+	loanApplications, err := getLoanApplicationsByIndex(stub, reviewerIndexName, oldReviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := reassignResult{Locked: []string{}}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.ReviewerID != oldReviewerID || !openForReview(loanApplication.Status) {
+			continue
+		}
+		if loanApplication.Locked {
+			result.Locked = append(result.Locked, loanApplication.ID)
+			continue
+		}
+		err = delIndexEntry(stub, reviewerIndexName, []string{oldReviewerID, loanApplication.ID})
+		if err != nil {
+			return nil, err
+		}
+		loanApplication.ReviewerID = newReviewerID
+		err = setStatus(stub, &loanApplication, loanApplication.Status, "reassigned from "+oldReviewerID+" to "+newReviewerID)
+		if err != nil {
+			return nil, err
+		}
+		err = saveLoanApplication(stub, &loanApplication)
+		if err != nil {
+			return nil, err
+		}
+		err = putIndexEntry(stub, reviewerIndexName, []string{newReviewerID, loanApplication.ID})
+		if err != nil {
+			return nil, err
+		}
+		result.Reassigned++
+	}
+
+	count := strconv.Itoa(result.Reassigned)
+	err = sendSummaryEvent(stub, "reviewerQueueReassigned", count+" loan applications reassigned from "+oldReviewerID+" to "+newReviewerID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Reassigned " + count + " loan applications from " + oldReviewerID + " to " + newReviewerID)
+	return json.Marshal(result)
+}
+
+// GetReviewQueueByRisk Get the calling reviewer's applications under review, highest risk score first
+func GetReviewQueueByRisk(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetReviewQueueByRisk")
+
+	username, err := GetCertAttribute(stub, "username")
+	if err != nil {
+		return nil, err
+	}
+
+	loanApplications, err := getLoanApplicationsByIndex(stub, reviewerIndexName, username)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := []LoanApplication{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.Status == statusUnderReview {
+			queue = append(queue, loanApplication)
+		}
+	}
+	// Ties keep index order, which is by application ID
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].RiskScore > queue[j].RiskScore
+	})
+
+	return marshalLoanApplications(stub, queue)
+}
+
+// reviewerDecisionStats Decisions made by one reviewer
+type reviewerDecisionStats struct {
+	Approved           int     `json:"approved"`
+	Rejected           int     `json:"rejected"`
+	AvgTurnaroundHours float64 `json:"avgTurnaroundHours"`
+	timedDecisions     int
+	totalHours         float64
+}
+
+// GetReviewerDecisionStats Get each reviewer's approval and rejection counts and average business hours
+// from being assigned an application to deciding it
+func GetReviewerDecisionStats(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetReviewerDecisionStats")
+
+	calendar, err := getBusinessCalendar(stub)
+	if err != nil {
+		return nil, err
+	}
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[string]*reviewerDecisionStats{}
+	for _, loanApplication := range loanApplications {
+		var assigned time.Time
+		for _, change := range ownStatusHistory(loanApplication) {
+			date, err := time.Parse(time.RFC3339, change.Date)
+			if change.Status == statusUnderReview {
+				if err == nil {
+					assigned = date
+				}
+				continue
+			}
+			if change.Status != statusApproved && change.Status != statusRejected {
+				continue
+			}
+			// Automated decisions are not attributed to a reviewer
+			if change.ReviewerID == "" || change.ReviewerID == systemReviewerID {
+				continue
+			}
+
+			reviewerStats, ok := stats[change.ReviewerID]
+			if !ok {
+				reviewerStats = &reviewerDecisionStats{}
+				stats[change.ReviewerID] = reviewerStats
+			}
+			if change.Status == statusApproved {
+				reviewerStats.Approved++
+			} else {
+				reviewerStats.Rejected++
+			}
+			if err == nil && !assigned.IsZero() {
+				reviewerStats.totalHours += calendar.businessHoursBetween(assigned, date)
+				reviewerStats.timedDecisions++
+			}
+		}
+	}
+	for _, reviewerStats := range stats {
+		if reviewerStats.timedDecisions > 0 {
+			reviewerStats.AvgTurnaroundHours = reviewerStats.totalHours / float64(reviewerStats.timedDecisions)
+		}
+	}
+
+	return json.Marshal(stats)
+}
+
+// GetStaleAssignments Get applications under review untouched for more business hours than the review SLA, grouped by reviewer
+func GetStaleAssignments(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetStaleAssignments")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	if params.ReviewSLAHours <= 0 {
+		return nil, errors.New("Review SLA is not configured")
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	calendar, err := getBusinessCalendar(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	view, err := loanApplicationViewer(stub)
+	if err != nil {
+		return nil, err
+	}
+	stale := map[string][]json.RawMessage{}
+	for _, loanApplication := range loanApplications {
+		if loanApplication.Status != statusUnderReview {
+			continue
+		}
+		modified, err := time.Parse(time.RFC3339, loanApplication.LastModifiedDate)
+		if err != nil {
+			logger.Warning("Skipping loan application " + loanApplication.ID + " with unparseable last modified date")
+			continue
+		}
+		if calendar.businessHoursBetween(modified, now) > float64(params.ReviewSLAHours) {
+			record, err := view(loanApplication)
+			if err != nil {
+				return nil, err
+			}
+			stale[loanApplication.ReviewerID] = append(stale[loanApplication.ReviewerID], record)
+		}
+	}
+
+	bytes, err := json.Marshal(stale)
+	if err != nil {
+		logger.Error("Could not marshal stale assignments", err)
+		return nil, err
+	}
+	return bytes, nil
+}
+
+// applicationEscalation Payload of the applicationEscalated event
+type applicationEscalation struct {
+	ApplicationID string `json:"applicationId"`
+	ReviewerID    string `json:"reviewerId"`
+	Reason        string `json:"reason"`
+}
+
+// escalatableStatuses Statuses of applications still open for review, which escalation may return to UnderReview
+// without losing a decision
+var escalatableStatuses = map[string]bool{
+	statusSubmitted:   true,
+	statusUnderReview: true,
+}
+
+// EscalateApplication Flag application args[0] as escalated for reason args[1], optionally reassigning
+// it to senior reviewer args[2]
+func EscalateApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering EscalateApplication")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID and escalation reason")
+	}
+
+	var loanAppID = args[0]
+	var reason = args[1]
+	var seniorReviewerID string
+	if len(args) > 2 {
+		seniorReviewerID = args[2]
+	}
+
+	if reason == "" {
+		return nil, errors.New("Escalation reason cannot be empty")
+	}
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	if !escalatableStatuses[loanApplication.Status] {
+		return nil, errors.New("Loan application " + loanAppID + " is " + loanApplication.Status + " and cannot be escalated")
+	}
+	if loanApplication.Escalated {
+		return nil, errors.New("Loan application " + loanAppID + " is already escalated")
+	}
+
+	loanApplication.Escalated = true
+	loanApplication.EscalationReason = reason
+	status := loanApplication.Status
+	if seniorReviewerID != "" && seniorReviewerID != loanApplication.ReviewerID {
+		roster, err := getReviewerRoster(stub)
+		if err != nil {
+			return nil, err
+		}
+		if !roster.isActiveSeniorReviewer(seniorReviewerID) {
+			return nil, errors.New("Reviewer " + seniorReviewerID + " is not an active senior reviewer")
+		}
+		if loanApplication.ReviewerID != "" {
+			err = delIndexEntry(stub, reviewerIndexName, []string{loanApplication.ReviewerID, loanAppID})
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = putIndexEntry(stub, reviewerIndexName, []string{seniorReviewerID, loanAppID})
+		if err != nil {
+			return nil, err
+		}
+		loanApplication.ReviewerID = seniorReviewerID
+		status = statusUnderReview
+	}
+	err = setStatus(stub, &loanApplication, status, "escalated: "+reason)
+	if err != nil {
+		return nil, err
+	}
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "applicationEscalated", loanAppID+" escalated", applicationEscalation{
+		ApplicationID: loanAppID,
+		ReviewerID:    loanApplication.ReviewerID,
+		Reason:        reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully escalated loan application")
+	return nil, nil
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// testRoster Roster of reviewers rev1 and rev2, the senior reviewer senior1 and the inactive reviewer rev3
+const testRoster = `{"reviewers":[{"id":"rev1","active":true},{"id":"rev2","active":true},` +
+	`{"id":"senior1","active":true,"senior":true},{"id":"rev3","active":false}]}`
+
+func TestAutoAssignReviewerChoosesLeastLoaded(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	for _, id := range []string{"LA-1", "LA-2", "LA-3", "LA-4", "LA-5"} {
+		seedApplication(t, stub, testApplication(id))
+	}
+	stub.mustInvoke(t, "AssignReviewer", "LA-1", "rev1")
+	stub.mustInvoke(t, "AssignReviewer", "LA-2", "rev1")
+	stub.mustInvoke(t, "AssignReviewer", "LA-3", "rev2")
+	stub.mustInvoke(t, "AssignReviewer", "LA-4", "senior1")
+
+	// Decided applications no longer count towards a reviewer's load
+	stub.as("rev2", roleReviewer).mustInvoke(t, "RejectLoanApplication", "LA-3", "INCOMPLETE_DOCS", "incomplete")
+	stub.as("admin", roleAdmin)
+
+	chosen := stub.mustInvoke(t, "AutoAssignReviewer", "LA-5")
+	if string(chosen) != "rev2" {
+		t.Fatalf("expected rev2 to be chosen, got %s", chosen)
+	}
+	loanApplication := storedApplication(t, stub, "LA-5")
+	if loanApplication.ReviewerID != "rev2" || loanApplication.Status != statusUnderReview {
+		t.Fatalf("expected LA-5 under review by rev2, got %s by %s", loanApplication.Status, loanApplication.ReviewerID)
+	}
+}
+
+func TestAutoAssignReviewerRequiresOpenApplication(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	approved := testApplication("LA-1")
+	approved.Status = statusApproved
+	seedApplication(t, stub, approved)
+
+	if _, err := stub.invoke("AutoAssignReviewer", "LA-1"); err == nil {
+		t.Fatal("expected an approved application not to be assigned a reviewer")
+	}
+	if storedApplication(t, stub, "LA-1").Status != statusApproved {
+		t.Fatal("expected the application to stay approved")
+	}
+}
+
+func TestGetLoanApplicationCountByReviewer(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	for _, id := range []string{"LA-1", "LA-2", "LA-3", "LA-4"} {
+		seedApplication(t, stub, testApplication(id))
+	}
+	stub.mustInvoke(t, "AssignReviewer", "LA-1", "rev1")
+	stub.mustInvoke(t, "AssignReviewer", "LA-2", "rev1")
+	stub.mustInvoke(t, "AssignReviewer", "LA-3", "rev2")
+	stub.mustInvoke(t, "AssignReviewer", "LA-4", "rev2")
+	stub.as("rev2", roleReviewer).mustInvoke(t, "RejectLoanApplication", "LA-4", "INCOMPLETE_DOCS", "incomplete")
+	stub.as("admin", roleAdmin)
+
+	var counts map[string]int
+	err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationCountByReviewer"), &counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"rev1": 2, "rev2": 1, "senior1": 0, "rev3": 0}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected counts %v, got %v", expected, counts)
+	}
+	for reviewerID, count := range expected {
+		if counts[reviewerID] != count {
+			t.Errorf("expected %s to own %d, got %d", reviewerID, count, counts[reviewerID])
+		}
+	}
+}
+
+func TestReassignAllForReviewer(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	for _, id := range []string{"LA-1", "LA-2", "LA-3"} {
+		seedApplication(t, stub, testApplication(id))
+		stub.mustInvoke(t, "AssignReviewer", id, "rev1")
+	}
+
+	var result reassignResult
+	err := json.Unmarshal(stub.mustInvoke(t, "ReassignAllForReviewer", "rev1", "rev2"), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reassigned != 3 || len(result.Locked) != 0 {
+		t.Fatalf("expected all 3 reassigned, got %+v", result)
+	}
+	if keys, _ := getKeysByPartialCompositeKey(stub, reviewerIndexName, []string{"rev1"}); len(keys) != 0 {
+		t.Fatalf("expected the old reviewer's index to be empty, got %d entries", len(keys))
+	}
+	if keys, _ := getKeysByPartialCompositeKey(stub, reviewerIndexName, []string{"rev2"}); len(keys) != 3 {
+		t.Fatalf("expected the new reviewer to hold 3 entries, got %d", len(keys))
+	}
+	for _, id := range []string{"LA-1", "LA-2", "LA-3"} {
+		if loanApplication := storedApplication(t, stub, id); loanApplication.ReviewerID != "rev2" || loanApplication.Status != statusUnderReview {
+			t.Errorf("expected %s under review by rev2, got %s by %s", id, loanApplication.Status, loanApplication.ReviewerID)
+		}
+	}
+}
+
+func TestReassignAllForReviewerSkipsDecidedAndLocked(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	for _, id := range []string{"LA-1", "LA-2", "LA-3"} {
+		seedApplication(t, stub, testApplication(id))
+		stub.mustInvoke(t, "AssignReviewer", id, "rev1")
+	}
+	stub.mustInvoke(t, "LockLoanApplication", "LA-2")
+	stub.as("rev1", roleReviewer).mustInvoke(t, "RejectLoanApplication", "LA-3", "INCOMPLETE_DOCS", "incomplete")
+	stub.as("admin", roleAdmin)
+
+	var result reassignResult
+	err := json.Unmarshal(stub.mustInvoke(t, "ReassignAllForReviewer", "rev1", "rev2"), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reassigned != 1 || len(result.Locked) != 1 || result.Locked[0] != "LA-2" {
+		t.Fatalf("expected LA-1 reassigned and LA-2 reported locked, got %+v", result)
+	}
+	if reviewerID := storedApplication(t, stub, "LA-3").ReviewerID; reviewerID != "rev1" {
+		t.Fatalf("expected the rejected LA-3 to keep its deciding reviewer, got %s", reviewerID)
+	}
+	if _, err := stub.invoke("ReassignAllForReviewer", "rev1", "rev3"); err == nil {
+		t.Fatal("expected reassignment to an inactive reviewer to be rejected")
+	}
+}
+
+func TestGetReviewQueueByRiskSortsDescending(t *testing.T) {
+	stub := newMockStub()
+	for id, score := range map[string]int{"LA-1": 20, "LA-2": 75, "LA-3": 40, "LA-4": 75, "LA-5": 90} {
+		loanApplication := testApplication(id)
+		loanApplication.Status = statusUnderReview
+		loanApplication.ReviewerID = "rev1"
+		loanApplication.RiskScore = score
+		seedApplication(t, stub, loanApplication)
+	}
+	decided := testApplication("LA-6")
+	decided.Status = statusApproved
+	decided.ReviewerID = "rev1"
+	decided.RiskScore = 99
+	seedApplication(t, stub, decided)
+	otherReviewer := testApplication("LA-7")
+	otherReviewer.Status = statusUnderReview
+	otherReviewer.ReviewerID = "rev2"
+	otherReviewer.RiskScore = 95
+	seedApplication(t, stub, otherReviewer)
+
+	assertIDs(t, recordIDs(t, stub.as("rev1", roleReviewer).mustQuery(t, "GetReviewQueueByRisk")), "LA-5", "LA-2", "LA-4", "LA-3", "LA-1")
+}
+
+func TestGetReviewerDecisionStats(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	start := stub.now
+
+	decisions := []struct {
+		id       string
+		reviewer string
+		hours    int
+		approve  bool
+	}{
+		{"LA-1", "rev1", 4, true},
+		{"LA-2", "rev1", 8, false},
+		{"LA-3", "rev2", 2, true},
+	}
+	for _, decision := range decisions {
+		stub.now = start
+		seedApplication(t, stub, testApplication(decision.id))
+		stub.as("admin", roleAdmin).mustInvoke(t, "AssignReviewer", decision.id, decision.reviewer)
+		stub.now = start.Add(time.Duration(decision.hours) * time.Hour)
+		stub.as(decision.reviewer, roleReviewer)
+		if decision.approve {
+			stub.mustInvoke(t, "ApproveLoanApplication", decision.id, "", "", "n"+decision.id)
+		} else {
+			stub.mustInvoke(t, "RejectLoanApplication", decision.id, "DTI_TOO_HIGH", "over the limit")
+		}
+	}
+
+	var stats map[string]reviewerDecisionStats
+	err := json.Unmarshal(stub.as("admin", roleAdmin).mustQuery(t, "GetReviewerDecisionStats"), &stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for two reviewers, got %+v", stats)
+	}
+	if rev1 := stats["rev1"]; rev1.Approved != 1 || rev1.Rejected != 1 || rev1.AvgTurnaroundHours != 6 {
+		t.Errorf("expected rev1 to have 1 approval and 1 rejection averaging 6 hours, got %+v", rev1)
+	}
+	if rev2 := stats["rev2"]; rev2.Approved != 1 || rev2.Rejected != 0 || rev2.AvgTurnaroundHours != 2 {
+		t.Errorf("expected rev2 to have 1 approval in 2 hours, got %+v", rev2)
+	}
+}
+
+func TestAssignReviewerEmitsReviewerNotification(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	stub.mustInvoke(t, "AssignReviewer", "LA-1", "rev2")
+	var evt struct {
+		Type    string               `json:"type"`
+		Payload reviewerNotification `json:"payload"`
+	}
+	err := json.Unmarshal(stub.lastEvent(t).payload, &evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.Type != "reviewerNotification" || evt.Payload.ReviewerID != "rev2" || evt.Payload.ApplicationID != "LA-1" {
+		t.Fatalf("expected a notification for rev2 about LA-1, got %+v", evt)
+	}
+}
+
+func TestFailedAssignmentEmitsNoNotification(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	seedApplication(t, stub, testApplication("LA-1"))
+	rejected := testApplication("LA-2")
+	rejected.Status = statusRejected
+	seedApplication(t, stub, rejected)
+	events := len(stub.events)
+
+	// Called directly so the stub's transaction rollback cannot hide an event the handler set
+	for _, args := range [][]string{{"LA-1", "rev3"}, {"LA-1", "nobody"}, {"LA-2", "rev1"}} {
+		if _, err := AssignReviewer(stub, args); err == nil {
+			t.Errorf("expected assignment %v to fail", args)
+		}
+	}
+	if len(stub.events) != events {
+		t.Fatalf("expected no notification from failed assignments, got %d events", len(stub.events)-events)
+	}
+}
+
+func TestGetStaleAssignmentsGroupsBreachedReviews(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"reviewSlaHours":48}`)
+	seeds := []struct {
+		id, status, reviewer string
+		idleHours            int
+	}{
+		{"LA-1", statusUnderReview, "rev1", 49},
+		{"LA-2", statusUnderReview, "rev1", 48},
+		{"LA-3", statusUnderReview, "rev1", 100},
+		{"LA-4", statusUnderReview, "rev2", 72},
+		{"LA-5", statusSubmitted, "", 200},
+		{"LA-6", statusApproved, "rev2", 200},
+	}
+	for _, seed := range seeds {
+		loanApplication := testApplication(seed.id)
+		loanApplication.Status = seed.status
+		loanApplication.ReviewerID = seed.reviewer
+		loanApplication.LastModifiedDate = stub.now.Add(-time.Duration(seed.idleHours) * time.Hour).Format(time.RFC3339)
+		seedApplication(t, stub, loanApplication)
+	}
+
+	var stale map[string]json.RawMessage
+	err := json.Unmarshal(stub.mustQuery(t, "GetStaleAssignments"), &stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 2 {
+		t.Fatalf("expected stale reviews for two reviewers, got %v", stale)
+	}
+	assertIDs(t, recordIDs(t, stale["rev1"]), "LA-1", "LA-3")
+	assertIDs(t, recordIDs(t, stale["rev2"]), "LA-4")
+}
+
+func TestGetStaleAssignmentsRequiresSLA(t *testing.T) {
+	stub := newMockStub()
+
+	if _, err := stub.query("GetStaleAssignments"); err == nil {
+		t.Fatal("expected an unconfigured review SLA to be rejected")
+	}
+}
+
+func TestEscalateApplicationFlagsAndReassigns(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+
+	stub.as("senior1", roleSeniorReviewer).mustInvoke(t, "EscalateApplication", "LA-1", "SLA breached", "senior1")
+
+	loanApplication := storedApplication(t, stub, "LA-1")
+	if !loanApplication.Escalated || loanApplication.EscalationReason != "SLA breached" || loanApplication.ReviewerID != "senior1" {
+		t.Fatalf("expected LA-1 escalated to senior1, got %+v", loanApplication)
+	}
+	if keys, _ := getKeysByPartialCompositeKey(stub, reviewerIndexName, []string{"rev1"}); len(keys) != 0 {
+		t.Fatal("expected the previous reviewer's index entry to be removed")
+	}
+	var evt struct {
+		Type    string                `json:"type"`
+		Payload applicationEscalation `json:"payload"`
+	}
+	err := json.Unmarshal(stub.lastEvent(t).payload, &evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.Type != "applicationEscalated" || evt.Payload != (applicationEscalation{ApplicationID: "LA-1", ReviewerID: "senior1", Reason: "SLA breached"}) {
+		t.Fatalf("unexpected escalation event %+v", evt)
+	}
+
+	if _, err := stub.invoke("EscalateApplication", "LA-1", "again"); err == nil {
+		t.Fatal("expected a second escalation to be rejected")
+	}
+}
+
+func TestEscalateApplicationRestrictions(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+
+	if _, err := stub.as("rev1", roleReviewer).invoke("EscalateApplication", "LA-1", "slow"); err == nil {
+		t.Fatal("expected reviewers to be denied")
+	}
+	stub.as("admin", roleAdmin)
+	if _, err := stub.invoke("EscalateApplication", "LA-1", "slow", "rev2"); err == nil {
+		t.Fatal("expected reassignment to a non-senior reviewer to be rejected")
+	}
+	stub.mustInvoke(t, "EscalateApplication", "LA-1", "slow")
+	if loanApplication := storedApplication(t, stub, "LA-1"); !loanApplication.Escalated || loanApplication.ReviewerID != "rev1" {
+		t.Fatalf("expected an escalation without reassignment to keep rev1, got %+v", loanApplication)
+	}
+}
+
+func TestEscalateApplicationRejectsDecided(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetReviewerRoster", testRoster)
+	for _, status := range []string{statusApproved, statusConditionallyApproved, statusPendingSecondApproval, statusRejected} {
+		seedApproved(t, stub, "LA-1", 300000)
+		loanApplication := storedApplication(t, stub, "LA-1")
+		loanApplication.Status = status
+		seedApplication(t, stub, loanApplication)
+
+		if _, err := stub.invoke("EscalateApplication", "LA-1", "late review", "senior1"); err == nil {
+			t.Fatalf("expected escalating a %s application to be rejected", status)
+		}
+		if loanApplication := storedApplication(t, stub, "LA-1"); loanApplication.Status != status || loanApplication.ReviewerID != "rev1" || loanApplication.Escalated {
+			t.Fatalf("expected the %s application untouched, got %+v", status, loanApplication)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// RiskWeights Risk score weighting. Each factor contributes up to its weight, for a 0-100 score
+// where higher is riskier:
+//   - debt-to-income, scaled against the maximum allowed ratio
+//   - loan-to-value, scaled against the maximum allowed ratio
+//   - applicant age, the full weight when outside the allowed range
+//   - documents, in proportion to the required types missing
+type RiskWeights struct {
+	DebtToIncome int `json:"debtToIncome"`
+	LoanToValue  int `json:"loanToValue"`
+	Age          int `json:"age"`
+	Documents    int `json:"documents"`
+}
+
+// defaultRiskWeights Weights used until the loan parameters set them
+var defaultRiskWeights = RiskWeights{DebtToIncome: 35, LoanToValue: 35, Age: 10, Documents: 20}
+
+// riskWeights The configured risk weights, or the defaults when none are set
+func (p LoanParameters) riskWeights() RiskWeights {
+	if p.RiskWeights == (RiskWeights{}) {
+		return defaultRiskWeights
+	}
+	return p.RiskWeights
+}
+
+// validateRiskWeights Ensure configured weights are non-negative and total 100
+func validateRiskWeights(weights RiskWeights) error {
+	if weights == (RiskWeights{}) {
+		return nil
+	}
+	if weights.DebtToIncome < 0 || weights.LoanToValue < 0 || weights.Age < 0 || weights.Documents < 0 {
+		return errors.New("Risk weights cannot be negative")
+	}
+	if weights.DebtToIncome+weights.LoanToValue+weights.Age+weights.Documents != 100 {
+		return errors.New("Risk weights must total 100")
+	}
+	return nil
+}
+
+// Reference limits used for scoring when the corresponding parameter is not set
+const (
+	referenceMaxDebtToIncome = 0.5
+	referenceMaxLoanToValue  = 0.9
+	referenceMinAge          = 18
+	referenceMaxAge          = 75
+)
+
+// scaledRisk Ratio of value to limit capped at one, times the weight
+func scaledRisk(value float64, limit float64, weight float64) float64 {
+	return math.Min(value/limit, 1) * weight
+}
+
+// ComputeRiskScore Score an application from 0 (lowest risk) to 100. Age is taken at
+// the application's last modification so the score is reproducible on every peer.
+func ComputeRiskScore(app LoanApplication, params LoanParameters) int {
+	weights := params.riskWeights()
+	maxDTI := params.MaxDebtToIncome
+	if maxDTI <= 0 {
+		maxDTI = referenceMaxDebtToIncome
+	}
+	maxLTV := params.MaxLoanToValue
+	if maxLTV <= 0 {
+		maxLTV = referenceMaxLoanToValue
+	}
+	minAge, maxAge := params.MinApplicantAge, params.MaxApplicantAge
+	if minAge <= 0 {
+		minAge = referenceMinAge
+	}
+	if maxAge <= 0 {
+		maxAge = referenceMaxAge
+	}
+
+	score := 0.0
+
+	if app.FinancialInfo.MonthlySalary <= 0 {
+		score += float64(weights.DebtToIncome)
+	} else {
+		score += scaledRisk(debtToIncome(app), maxDTI, float64(weights.DebtToIncome))
+	}
+
+	// An LTV that cannot be computed, unappraised or missing an FX rate, carries the full weight
+	if ltv, err := loanToValue(app, params); app.FairMarketValue <= 0 || err != nil {
+		score += float64(weights.LoanToValue)
+	} else {
+		score += scaledRisk(ltv, maxLTV, float64(weights.LoanToValue))
+	}
+
+	asOf, err := time.Parse(time.RFC3339, app.LastModifiedDate)
+	if err != nil {
+		asOf, err = time.Parse(time.RFC3339, app.CreatedDate)
+	}
+	age, ageErr := applicantAge(app, asOf)
+	if err != nil || ageErr != nil || age < minAge || age > maxAge {
+		score += float64(weights.Age)
+	}
+
+	if required := len(params.RequiredDocumentTypes); required > 0 {
+		missing := len(missingDocumentTypes(app, params.RequiredDocumentTypes))
+		score += float64(missing) / float64(required) * float64(weights.Documents)
+	}
+
+	return int(math.Round(score))
+}
+
+// RecomputeAllRiskScores Rescore every application under the current parameters and weights. Only the
+// score is rewritten, so last modified dates and the expiry and SLA clocks they drive are unchanged.
+func RecomputeAllRiskScores(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering RecomputeAllRiskScores")
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := 0
+	for _, loanApplication := range loanApplications {
+		score := ComputeRiskScore(loanApplication, params)
+		if score == loanApplication.RiskScore {
+			continue
+		}
+		loanApplication.RiskScore = score
+		err = putLoanApplication(stub, &loanApplication, now)
+		if err != nil {
+			return nil, err
+		}
+		changed++
+	}
+
+	err = sendSummaryEvent(stub, "riskScoresRecomputed", "Risk score changed for "+strconv.Itoa(changed)+" of "+strconv.Itoa(len(loanApplications))+" loan applications", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Recomputed risk scores, " + strconv.Itoa(changed) + " changed")
+	return json.Marshal(map[string]int{"changed": changed, "total": len(loanApplications)})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRiskScoreFollowsInputs(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+
+	// Loan-to-value of 0.6 against the 0.9 reference contributes two thirds of its 35 weight
+	if score := storedApplication(t, stub, "LA-1").RiskScore; score != 23 {
+		t.Fatalf("expected an initial score of 23, got %d", score)
+	}
+
+	stub.mustInvoke(t, "PatchLoanApplication", "LA-1", `{"requestedAmount":450000}`)
+	if score := storedApplication(t, stub, "LA-1").RiskScore; score != 35 {
+		t.Fatalf("expected a capped loan-to-value to score 35, got %d", score)
+	}
+
+	stub.mustInvoke(t, "PatchLoanApplication", "LA-1", `{"financialInfo":{"monthlyLoanPayment":2500}}`)
+	if score := storedApplication(t, stub, "LA-1").RiskScore; score != 53 {
+		t.Fatalf("expected debt-to-income to raise the score to 53, got %d", score)
+	}
+
+	stub.mustInvoke(t, "PatchLoanApplication", "LA-1", `{"personalInfo":{"DOB":"2010-01-01"}}`)
+	if score := storedApplication(t, stub, "LA-1").RiskScore; score != 63 {
+		t.Fatalf("expected an underage applicant to add the age weight, got %d", score)
+	}
+}
+
+func TestComputeRiskScoreMissingDocuments(t *testing.T) {
+	params := LoanParameters{RequiredDocumentTypes: []string{"payslip", "valuation"}}
+	loanApplication := testApplication("LA-1")
+	loanApplication.LastModifiedDate = "2024-03-15T12:00:00Z"
+
+	if score := ComputeRiskScore(loanApplication, params); score != 43 {
+		t.Fatalf("expected every document missing to add 20, got %d", score)
+	}
+	loanApplication.Documents = []Document{{Type: "payslip"}}
+	if score := ComputeRiskScore(loanApplication, params); score != 33 {
+		t.Fatalf("expected one of two documents missing to add 10, got %d", score)
+	}
+}
+
+func TestRecomputeAllRiskScoresAfterWeightChange(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+	unappraised := testApplication("LA-2")
+	unappraised.FairMarketValue = 0
+	createApplication(t, stub, unappraised)
+	if storedApplication(t, stub, "LA-1").RiskScore != 23 || storedApplication(t, stub, "LA-2").RiskScore != 35 {
+		t.Fatal("expected scores under the default weights")
+	}
+	modified := storedApplication(t, stub, "LA-1").LastModifiedDate
+	stub.now = stub.now.Add(time.Hour)
+
+	stub.mustInvoke(t, "SetLoanParameters", `{"riskWeights":{"debtToIncome":10,"loanToValue":70,"age":10,"documents":10}}`)
+	var result map[string]int
+	err := json.Unmarshal(stub.mustInvoke(t, "RecomputeAllRiskScores"), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["changed"] != 2 || result["total"] != 2 {
+		t.Fatalf("expected both scores to change, got %v", result)
+	}
+	if score := storedApplication(t, stub, "LA-1").RiskScore; score != 47 {
+		t.Fatalf("expected two thirds of the 70 loan-to-value weight, got %d", score)
+	}
+	if score := storedApplication(t, stub, "LA-2").RiskScore; score != 70 {
+		t.Fatalf("expected the full loan-to-value weight for an unappraised application, got %d", score)
+	}
+	if storedApplication(t, stub, "LA-1").LastModifiedDate != modified {
+		t.Fatal("expected rescoring to keep the last modified date")
+	}
+	var evt customEvent
+	json.Unmarshal(stub.lastEvent(t).payload, &evt)
+	if evt.Type != "riskScoresRecomputed" || evt.Decription != "Risk score changed for 2 of 2 loan applications" {
+		t.Fatalf("unexpected summary event %+v", evt)
+	}
+
+	err = json.Unmarshal(stub.mustInvoke(t, "RecomputeAllRiskScores"), &result)
+	if err != nil || result["changed"] != 0 {
+		t.Fatalf("expected a rerun to change nothing, got %v, %v", result, err)
+	}
+	if _, err := stub.as("rev1", roleReviewer).invoke("RecomputeAllRiskScores"); err == nil {
+		t.Fatal("expected non-admins to be denied")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// jsonSchemaDraft JSON Schema dialect of the generated document
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// jsonSchemaFor Describe a Go type as JSON Schema, reading field names from json tags and
+// constraints from validate tags so the schema follows the struct as it changes
+func jsonSchemaFor(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchemaFor(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			schema := jsonSchemaFor(field.Type)
+			for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+				switch {
+				case rule == "required":
+					required = append(required, name)
+				case rule == "email":
+					schema["format"] = "email"
+				case rule == "date":
+					schema["format"] = "date"
+				case rule == "currency":
+					currencies := []string{}
+					for currency := range validCurrencies {
+						currencies = append(currencies, currency)
+					}
+					sort.Strings(currencies)
+					schema["enum"] = currencies
+				case strings.HasPrefix(rule, "min="):
+					if min, err := strconv.ParseInt(strings.TrimPrefix(rule, "min="), 10, 64); err == nil {
+						schema["minimum"] = min
+					}
+				}
+			}
+			properties[name] = schema
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		// Free-form values such as interface{} accept anything
+		return map[string]interface{}{}
+	}
+}
+
+// GetLoanApplicationJSONSchema Get a JSON Schema document describing the loan application payload
+func GetLoanApplicationJSONSchema(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationJSONSchema")
+
+	schema := jsonSchemaFor(reflect.TypeOf(LoanApplication{}))
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "LoanApplication"
+	return json.Marshal(schema)
+}
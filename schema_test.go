@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// jsonSchemaDocument The parts of a JSON Schema the tests inspect
+type jsonSchemaDocument struct {
+	Schema     string                        `json:"$schema"`
+	Type       string                        `json:"type"`
+	Format     string                        `json:"format"`
+	Minimum    *int64                        `json:"minimum"`
+	Required   []string                      `json:"required"`
+	Properties map[string]jsonSchemaDocument `json:"properties"`
+}
+
+func TestGetLoanApplicationJSONSchemaRequiredFields(t *testing.T) {
+	stub := newMockStub()
+
+	var schema jsonSchemaDocument
+	err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationJSONSchema"), &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Schema != jsonSchemaDraft || schema.Type != "object" {
+		t.Fatalf("expected a draft-07 object schema, got %s %s", schema.Schema, schema.Type)
+	}
+
+	personalInfo := schema.Properties["personalInfo"]
+	assertSameIDs(t, personalInfo.Required, "firstname", "lastname", "DOB", "email", "mobile")
+	if personalInfo.Properties["email"].Format != "email" || personalInfo.Properties["DOB"].Format != "date" {
+		t.Fatal("expected email and date formats on personal information")
+	}
+	if minimum := schema.Properties["financialInfo"].Properties["monthlySalary"].Minimum; minimum == nil || *minimum != 0 {
+		t.Fatal("expected a minimum of 0 on the monthly salary")
+	}
+
+	// Every field of the struct appears in the schema
+	loanApplicationType := reflect.TypeOf(LoanApplication{})
+	for i := 0; i < loanApplicationType.NumField(); i++ {
+		name := strings.Split(loanApplicationType.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = loanApplicationType.Field(i).Name
+		}
+		if _, ok := schema.Properties[name]; !ok && name != "-" {
+			t.Errorf("expected field %s in the schema", name)
+		}
+	}
+}
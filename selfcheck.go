@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// selfCheckViolation One broken ledger invariant
+type selfCheckViolation struct {
+	Check  string `json:"check"`
+	ID     string `json:"id"`
+	Detail string `json:"detail"`
+}
+
+// SelfCheck Verify that every index entry points at a live application, every application under review
+// has a reviewer and every status is known, returning the violations found
+func SelfCheck(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SelfCheck")
+
+	violations := []selfCheckViolation{}
+
+	for _, index := range loanIndexes {
+		keys, err := getKeysByPartialCompositeKey(stub, index.name, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			_, attributes := splitCompositeKey(key)
+			if len(attributes) < 2 {
+				violations = append(violations, selfCheckViolation{Check: "malformedIndexEntry", Detail: index.name + " entry has " + strconv.Itoa(len(attributes)) + " attributes"})
+				continue
+			}
+			loanAppID := attributes[len(attributes)-1]
+			loanAppBytes, err := stub.GetState(loanKey(loanAppID))
+			if err != nil {
+				logger.Error("Could not fetch loan application "+loanAppID+" from ledger", err)
+				return nil, err
+			}
+			if loanAppBytes == nil {
+				violations = append(violations, selfCheckViolation{Check: "danglingIndexEntry", ID: loanAppID, Detail: index.name + " entry for " + attributes[0]})
+			}
+		}
+	}
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+	for _, loanApplication := range loanApplications {
+		if !validStatuses[loanApplication.Status] {
+			violations = append(violations, selfCheckViolation{Check: "unknownStatus", ID: loanApplication.ID, Detail: "status '" + loanApplication.Status + "'"})
+		}
+		if loanApplication.Status == statusUnderReview && loanApplication.ReviewerID == "" {
+			violations = append(violations, selfCheckViolation{Check: "missingReviewer", ID: loanApplication.ID, Detail: "under review without a reviewer"})
+		}
+	}
+
+	bytes, err := json.Marshal(violations)
+	if err != nil {
+		logger.Error("Could not marshal self check violations", err)
+		return nil, err
+	}
+	return bytes, nil
+}
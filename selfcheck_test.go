@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// selfCheckOf Violations reported by SelfCheck
+func selfCheckOf(t *testing.T, stub *mockStub) []selfCheckViolation {
+	t.Helper()
+	var violations []selfCheckViolation
+	err := json.Unmarshal(stub.mustQuery(t, "SelfCheck"), &violations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return violations
+}
+
+// assertOnlyCheck Fail unless violations are non-empty and all of the given check on the given application
+func assertOnlyCheck(t *testing.T, violations []selfCheckViolation, check string, loanAppID string) {
+	t.Helper()
+	if len(violations) == 0 {
+		t.Fatalf("expected a %s violation", check)
+	}
+	for _, violation := range violations {
+		if violation.Check != check || violation.ID != loanAppID {
+			t.Fatalf("expected only %s violations for %q, got %+v", check, loanAppID, violations)
+		}
+	}
+}
+
+func TestSelfCheckCleanLedger(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+	seedUnderReview(t, stub, testApplication("LA-2"))
+
+	if violations := selfCheckOf(t, stub); len(violations) != 0 {
+		t.Fatalf("expected no violations, got %+v", violations)
+	}
+}
+
+func TestSelfCheckReportsDanglingIndexEntries(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+	seedApplication(t, stub, testApplication("LA-2"))
+	stub.DelState(loanKey("LA-2"))
+
+	assertOnlyCheck(t, selfCheckOf(t, stub), "danglingIndexEntry", "LA-2")
+}
+
+func TestSelfCheckReportsMalformedIndexEntries(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+	putIndexEntry(stub, buyerIndexName, []string{"BUYER-X"})
+
+	assertOnlyCheck(t, selfCheckOf(t, stub), "malformedIndexEntry", "")
+}
+
+func TestSelfCheckReportsMissingReviewer(t *testing.T) {
+	stub := newMockStub()
+	unassigned := testApplication("LA-1")
+	unassigned.Status = statusUnderReview
+	seedApplication(t, stub, unassigned)
+
+	assertOnlyCheck(t, selfCheckOf(t, stub), "missingReviewer", "LA-1")
+}
+
+func TestSelfCheckReportsUnknownStatus(t *testing.T) {
+	stub := newMockStub()
+	unknown := testApplication("LA-1")
+	unknown.Status = "Pending"
+	seedApplication(t, stub, unknown)
+
+	assertOnlyCheck(t, selfCheckOf(t, stub), "unknownStatus", "LA-1")
+}
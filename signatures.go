@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const approverKeysKey = "approverKeys"
+
+// parseECDSAPublicKey Parse a PEM encoded PKIX ECDSA public key
+func parseECDSAPublicKey(keyPEM string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("Approver public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.New("Could not parse approver public key: " + err.Error())
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("Approver public key is not an ECDSA key")
+	}
+	return ecdsaKey, nil
+}
+
+// isRegisteredApproverKey Check the approver key list for the given key
+func isRegisteredApproverKey(stub shim.ChaincodeStubInterface, keyPEM string) (bool, error) {
+	var keys []string
+	_, err := getConfig(stub, approverKeysKey, &keys)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		if strings.TrimSpace(key) == strings.TrimSpace(keyPEM) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SetApproverKeys Replace the off-chain approver public keys with the JSON array of PEM keys in args[0]
+func SetApproverKeys(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SetApproverKeys")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected approver keys argument")
+	}
+
+	var keys []string
+	err := json.Unmarshal([]byte(args[0]), &keys)
+	if err != nil {
+		logger.Error("Could not unmarshal approver keys", err)
+		return nil, errors.New("Invalid approver keys: " + err.Error())
+	}
+	for _, key := range keys {
+		if _, err := parseECDSAPublicKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	err = putConfig(stub, approverKeysKey, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully saved approver keys")
+	return nil, nil
+}
+
+// SubmitSignedApproval Approve application args[0] on the strength of base64 ASN.1 ECDSA signature args[2]
+// by registered approver key args[1] over the stored record bytes, using replay nonce args[3]
+func SubmitSignedApproval(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SubmitSignedApproval")
+
+	if len(args) < 4 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID, approver public key, signature and nonce")
+	}
+
+	var loanAppID = args[0]
+	var keyPEM = args[1]
+
+	publicKey, err := parseECDSAPublicKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	registered, err := isRegisteredApproverKey(stub, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if !registered {
+		return nil, errors.New("Approver public key is not registered")
+	}
+	signature, err := base64.StdEncoding.DecodeString(args[2])
+	if err != nil {
+		return nil, errors.New("Signature is not valid base64")
+	}
+
+	laBytes, err := stub.GetState(loanKey(loanAppID))
+	if err != nil {
+		logger.Error("Could not fetch loan application with id "+loanAppID+" from ledger", err)
+		return nil, err
+	}
+	if laBytes == nil {
+		return nil, errors.New("Loan application " + loanAppID + " does not exist")
+	}
+
+	digest := sha256.Sum256(laBytes)
+	if !ecdsa.VerifyASN1(publicKey, digest[:], signature) {
+		logger.Error("Invalid approval signature for loan application " + loanAppID)
+		return nil, errors.New("Signature does not match loan application " + loanAppID + " for the given approver key")
+	}
+
+	return ApproveLoanApplication(stub, []string{loanAppID, "", "", args[3]})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+)
+
+// testApproverKey Generate an approver key pair and its PEM public key
+func testApproverKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// signRecord Sign the stored bytes of an application
+func signRecord(t *testing.T, key *ecdsa.PrivateKey, record []byte) string {
+	t.Helper()
+	digest := sha256.Sum256(record)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+func TestSubmitSignedApproval(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	key, publicKey := testApproverKey(t)
+	keys, _ := json.Marshal([]string{publicKey})
+	stub.mustInvoke(t, "SetApproverKeys", string(keys))
+	seedUnderReview(t, stub, testApplication("LA-1"))
+
+	signature := signRecord(t, key, stub.state[loanKey("LA-1")])
+	stub.mustInvoke(t, "SubmitSignedApproval", "LA-1", publicKey, signature, "n1")
+
+	if status := storedApplication(t, stub, "LA-1").Status; status != statusApproved {
+		t.Fatalf("expected a valid signature to approve, got %s", status)
+	}
+}
+
+func TestSubmitSignedApprovalRejectsTamperedSignature(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	key, publicKey := testApproverKey(t)
+	keys, _ := json.Marshal([]string{publicKey})
+	stub.mustInvoke(t, "SetApproverKeys", string(keys))
+	seedUnderReview(t, stub, testApplication("LA-1"))
+
+	// Signed over a record other than the one stored
+	tampered := append([]byte(nil), stub.state[loanKey("LA-1")]...)
+	tampered[len(tampered)-2] ^= 1
+	if _, err := stub.invoke("SubmitSignedApproval", "LA-1", publicKey, signRecord(t, key, tampered), "n1"); err == nil {
+		t.Fatal("expected a signature over different bytes to be rejected")
+	}
+
+	// Signed by a key that is not registered
+	otherKey, otherPublicKey := testApproverKey(t)
+	signature := signRecord(t, otherKey, stub.state[loanKey("LA-1")])
+	if _, err := stub.invoke("SubmitSignedApproval", "LA-1", otherPublicKey, signature, "n2"); err == nil {
+		t.Fatal("expected an unregistered key to be rejected")
+	}
+	if status := storedApplication(t, stub, "LA-1").Status; status != statusUnderReview {
+		t.Fatalf("expected the application to stay under review, got %s", status)
+	}
+}
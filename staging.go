@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// stagedWrite A buffered PutState or DelState
+type stagedWrite struct {
+	key    string
+	value  []byte
+	delete bool
+}
+
+// stagedStub Buffers writes and events until flush so a handler that fails part way
+// through leaves nothing behind, even when composed into a larger invocation.
+// Reads of staged keys see the staged value; range queries only see committed state.
+type stagedStub struct {
+	shim.ChaincodeStubInterface
+	writes  []stagedWrite
+	pending map[string]stagedWrite
+	event   *stagedWrite
+}
+
+// newStagedStub Wrap a stub so its writes are held until flush
+func newStagedStub(stub shim.ChaincodeStubInterface) *stagedStub {
+	return &stagedStub{ChaincodeStubInterface: stub, pending: map[string]stagedWrite{}}
+}
+
+// GetState Read a staged value if one exists, otherwise committed state
+func (s *stagedStub) GetState(key string) ([]byte, error) {
+	if write, ok := s.pending[key]; ok {
+		if write.delete {
+			return nil, nil
+		}
+		return write.value, nil
+	}
+	return s.ChaincodeStubInterface.GetState(key)
+}
+
+// PutState Stage a write
+func (s *stagedStub) PutState(key string, value []byte) error {
+	s.stage(stagedWrite{key: key, value: value})
+	return nil
+}
+
+// DelState Stage a delete
+func (s *stagedStub) DelState(key string) error {
+	s.stage(stagedWrite{key: key, delete: true})
+	return nil
+}
+
+// SetEvent Stage the transaction event
+func (s *stagedStub) SetEvent(name string, payload []byte) error {
+	s.event = &stagedWrite{key: name, value: payload}
+	return nil
+}
+
+func (s *stagedStub) stage(write stagedWrite) {
+	s.writes = append(s.writes, write)
+	s.pending[write.key] = write
+}
+
+// flush Apply every staged write in order, then the staged event
+func (s *stagedStub) flush() error {
+	for _, write := range s.writes {
+		var err error
+		if write.delete {
+			err = s.ChaincodeStubInterface.DelState(write.key)
+		} else {
+			err = s.ChaincodeStubInterface.PutState(write.key, write.value)
+		}
+		if err != nil {
+			logger.Error("Could not flush staged write to "+write.key, err)
+			return err
+		}
+	}
+	if s.event != nil {
+		return s.ChaincodeStubInterface.SetEvent(s.event.key, s.event.value)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCreateLateFailureLeavesNoState(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"maxOpenApplicationsPerProperty":1}`)
+	first := testApplication("first")
+	first.ID = ""
+	first.PropertyID = "PROP-1"
+	createApplication(t, stub, first)
+	before := len(stub.state)
+	counter := string(stub.state[loanIDCounterKey])
+
+	// Called directly so the stub's transaction rollback cannot hide writes the handler left behind
+	second := testApplication("second")
+	second.ID = ""
+	second.PropertyID = "PROP-1"
+	input, _ := json.Marshal(second)
+	if _, err := CreateLoanApplication(stub, []string{"", string(input)}); err == nil {
+		t.Fatal("expected the property cap to fail the create after an ID was generated")
+	}
+	if len(stub.state) != before || string(stub.state[loanIDCounterKey]) != counter {
+		t.Fatal("expected the failed create to leave no state, including the ID counter")
+	}
+}
+
+func TestStagedStubFlush(t *testing.T) {
+	stub := newMockStub()
+	stub.state["a"] = []byte("1")
+	staged := newStagedStub(stub)
+
+	staged.PutState("b", []byte("2"))
+	staged.DelState("a")
+	staged.SetEvent(eventName, []byte("event"))
+	if value, _ := staged.GetState("b"); string(value) != "2" {
+		t.Fatal("expected staged reads to see staged writes")
+	}
+	if value, _ := staged.GetState("a"); value != nil {
+		t.Fatal("expected staged reads to see staged deletes")
+	}
+	if stub.state["b"] != nil || stub.state["a"] == nil || len(stub.events) != 0 {
+		t.Fatal("expected nothing to reach the ledger before flush")
+	}
+
+	if err := staged.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if string(stub.state["b"]) != "2" || stub.state["a"] != nil || len(stub.events) != 1 {
+		t.Fatal("expected flush to apply every write and the event")
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// maxPageSize Upper bound on rows returned by a paged query
+const maxPageSize = 100
+
+// loanApplicationSummary Key fields of an application plus computed ratios
+type loanApplicationSummary struct {
+	ID                 string   `json:"id"`
+	Status             string   `json:"status"`
+	RequestedAmount    int64    `json:"requestedAmount"`
+	FairMarketValue    int64    `json:"fairMarketValue"`
+	ApprovedAmount     int64    `json:"approvedAmount"`
+	AffordabilityRatio float64  `json:"affordabilityRatio"`
+	DebtToIncome       float64  `json:"debtToIncome"`
+	LoanToValue        float64  `json:"loanToValue"`
+	RiskScore          int      `json:"riskScore"`
+	Eligible           bool     `json:"eligible"`
+	Reasons            []string `json:"reasons"`
+}
+
+// summaryPage A page of summaries with the bookmark for the next page
+type summaryPage struct {
+	Rows     []loanApplicationSummary `json:"rows"`
+	Bookmark string                   `json:"bookmark"`
+}
+
+// affordabilityRatio Monthly outgoings over monthly salary, zero when there is no salary
+func affordabilityRatio(loanApplication LoanApplication) (float64, error) {
+	info := loanApplication.FinancialInfo
+	if info.MonthlySalary <= 0 {
+		return 0, nil
+	}
+	outgoings, err := monthlyOutgoings(info)
+	if err != nil {
+		return 0, err
+	}
+	return float64(outgoings) / float64(info.MonthlySalary), nil
+}
+
+// loanToValue Requested amount over fair market value, converting the value into the application's
+// currency when they differ; zero when unappraised
+func loanToValue(loanApplication LoanApplication, params LoanParameters) (float64, error) {
+	if loanApplication.FairMarketValue <= 0 {
+		return 0, nil
+	}
+	fairMarketValue := loanApplication.FairMarketValue
+	if loanApplication.FairMarketValueCurrency != "" {
+		var err error
+		fairMarketValue, err = params.convert(fairMarketValue, loanApplication.FairMarketValueCurrency, loanApplication.Currency)
+		if err != nil {
+			return 0, err
+		}
+		if fairMarketValue <= 0 {
+			return 0, nil
+		}
+	}
+	return float64(loanApplication.RequestedAmount) / float64(fairMarketValue), nil
+}
+
+// summarize Build the summary of an application, previewing eligibility without mutating it
+func summarize(loanApplication LoanApplication, params LoanParameters, now time.Time) (loanApplicationSummary, error) {
+	affordability, err := affordabilityRatio(loanApplication)
+	if err != nil {
+		return loanApplicationSummary{}, errors.New("Loan application " + loanApplication.ID + ": " + err.Error())
+	}
+	// An LTV that cannot be computed is reported as zero and surfaces in the eligibility reasons
+	ltv, _ := loanToValue(loanApplication, params)
+	reasons := eligibilityReasons(loanApplication, params, now)
+	return loanApplicationSummary{
+		ID:                 loanApplication.ID,
+		Status:             loanApplication.Status,
+		RequestedAmount:    loanApplication.RequestedAmount,
+		FairMarketValue:    loanApplication.FairMarketValue,
+		ApprovedAmount:     loanApplication.ApprovedAmount,
+		AffordabilityRatio: affordability,
+		DebtToIncome:       debtToIncome(loanApplication),
+		LoanToValue:        ltv,
+		RiskScore:          ComputeRiskScore(loanApplication, params),
+		Eligible:           len(reasons) == 0,
+		Reasons:            reasons,
+	}, nil
+}
+
+// parsePageSize Validate a page size argument
+func parsePageSize(arg string) (int, error) {
+	pageSize, err := strconv.Atoi(arg)
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		return 0, errors.New("Page size must be an integer between 1 and " + strconv.Itoa(maxPageSize))
+	}
+	return pageSize, nil
+}
+
+// GetLoanApplicationSummary Get the summary and eligibility preview of application args[0]
+func GetLoanApplicationSummary(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationSummary")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	loanApplication, err := getLoanApplication(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := summarize(loanApplication, params, now)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := json.Marshal(&summary)
+	if err != nil {
+		logger.Error("Could not marshal loan application summary", err)
+		return nil, err
+	}
+	return bytes, nil
+}
+
+// applicationProgress Progress bar data for an application
+type applicationProgress struct {
+	ID       string `json:"id"`
+	Status   string `json:"status"`
+	Progress int    `json:"progress"`
+}
+
+// GetApplicationProgress Get how far application args[0] is through the workflow
+func GetApplicationProgress(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetApplicationProgress")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	loanApplication, err := getLoanApplication(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	progress, ok := statusProgress[loanApplication.Status]
+	if !ok {
+		return nil, errors.New("Loan application " + loanApplication.ID + " has unknown status '" + loanApplication.Status + "'")
+	}
+
+	return json.Marshal(applicationProgress{ID: loanApplication.ID, Status: loanApplication.Status, Progress: progress})
+}
+
+// GetLoanApplicationsSummaryPage Get a page of args[0] summaries starting after bookmark args[1]
+func GetLoanApplicationsSummaryPage(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsSummaryPage")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected page size and optional bookmark")
+	}
+
+	pageSize, err := parsePageSize(args[0])
+	if err != nil {
+		return nil, err
+	}
+	var bookmark string
+	if len(args) > 1 {
+		bookmark = args[1]
+	}
+
+	loanApplications, nextBookmark, err := getLoanApplicationsPage(stub, bookmark, pageSize, nil)
+	if err != nil {
+		return nil, err
+	}
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	page := summaryPage{Rows: []loanApplicationSummary{}, Bookmark: nextBookmark}
+	for _, loanApplication := range loanApplications {
+		summary, err := summarize(loanApplication, params, now)
+		if err != nil {
+			return nil, err
+		}
+		page.Rows = append(page.Rows, summary)
+	}
+
+	bytes, err := json.Marshal(&page)
+	if err != nil {
+		logger.Error("Could not marshal summary page", err)
+		return nil, err
+	}
+	return bytes, nil
+}
+
+// loanApplicationPage A page of applications, as the caller may see them, with the bookmark for the next page
+type loanApplicationPage struct {
+	Records  []json.RawMessage `json:"records"`
+	Bookmark string            `json:"bookmark"`
+}
+
+// GetLoanApplicationsByStatusPage Get a page of args[1] applications with status args[0] starting after bookmark args[2].
+// This shim has no rich queries, so pages come from a filtered range scan rather than a CouchDB selector.
+func GetLoanApplicationsByStatusPage(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsByStatusPage")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected status, page size and optional bookmark")
+	}
+
+	var status = args[0]
+	if !validStatuses[status] {
+		return nil, errors.New("Unknown loan application status '" + status + "'")
+	}
+	pageSize, err := parsePageSize(args[1])
+	if err != nil {
+		return nil, err
+	}
+	var bookmark string
+	if len(args) > 2 {
+		bookmark = args[2]
+	}
+
+	loanApplications, nextBookmark, err := getLoanApplicationsPage(stub, bookmark, pageSize, func(loanApplication LoanApplication) bool {
+		return loanApplication.Status == status
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := viewLoanApplications(stub, loanApplications, nil)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := json.Marshal(&loanApplicationPage{Records: records, Bookmark: nextBookmark})
+	if err != nil {
+		logger.Error("Could not marshal loan application page", err)
+		return nil, err
+	}
+	return bytes, nil
+}
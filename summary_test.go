@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetLoanApplicationsSummaryPage(t *testing.T) {
+	stub := newMockStub()
+	for _, id := range []string{"LA-1", "LA-2", "LA-3"} {
+		seedApplication(t, stub, testApplication(id))
+	}
+
+	var page summaryPage
+	err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationsSummaryPage", "2"), &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Rows) != 2 || page.Rows[0].ID != "LA-1" || page.Rows[1].ID != "LA-2" {
+		t.Fatalf("expected LA-1 and LA-2 on the first page, got %+v", page.Rows)
+	}
+	if page.Rows[0].AffordabilityRatio != 0.2 || page.Rows[0].LoanToValue != 0.6 {
+		t.Fatalf("expected computed ratios 0.2 and 0.6, got %+v", page.Rows[0])
+	}
+	if page.Bookmark != "LA-2" {
+		t.Fatalf("expected bookmark LA-2, got %q", page.Bookmark)
+	}
+
+	err = json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationsSummaryPage", "2", page.Bookmark), &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Rows) != 1 || page.Rows[0].ID != "LA-3" || page.Bookmark != "" {
+		t.Fatalf("expected only LA-3 and no bookmark on the last page, got %+v", page)
+	}
+}
+
+func TestGetLoanApplicationsSummaryPageSize(t *testing.T) {
+	stub := newMockStub()
+
+	for _, size := range []string{"0", "101", "ten"} {
+		if _, err := stub.query("GetLoanApplicationsSummaryPage", size); err == nil {
+			t.Errorf("expected page size %s to be rejected", size)
+		}
+	}
+}
+
+func TestGetLoanApplicationSummaryEligibility(t *testing.T) {
+	cases := map[string]struct {
+		modify func(loanApplication *LoanApplication)
+		reason string
+	}{
+		"eligible":      {func(la *LoanApplication) {}, ""},
+		"debtToIncome":  {func(la *LoanApplication) { la.FinancialInfo.MonthlyLoanPayment = 5000 }, "debt-to-income 0.50 exceeds maximum 0.40"},
+		"loanToValue":   {func(la *LoanApplication) { la.RequestedAmount = 450000 }, "loan-to-value 0.90 exceeds maximum 0.80"},
+		"applicantAge":  {func(la *LoanApplication) { la.PersonalInfo.DOB = "2008-01-01" }, "applicant age 16 is below minimum 18"},
+		"unappraised":   {func(la *LoanApplication) { la.FairMarketValue = 0 }, "no fair market value to assess loan-to-value"},
+		"missingSalary": {func(la *LoanApplication) { la.FinancialInfo.MonthlySalary = 0 }, "no monthly salary to assess debt-to-income"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			stub := newMockStub()
+			stub.mustInvoke(t, "SetLoanParameters", `{"maxDebtToIncome":0.4,"maxLoanToValue":0.8,"minApplicantAge":18}`)
+			loanApplication := testApplication("LA-1")
+			c.modify(&loanApplication)
+			seedApplication(t, stub, loanApplication)
+
+			var summary loanApplicationSummary
+			err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationSummary", "LA-1"), &summary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.reason == "" {
+				if !summary.Eligible || len(summary.Reasons) != 0 {
+					t.Fatalf("expected eligible, got %+v", summary)
+				}
+				return
+			}
+			if summary.Eligible || len(summary.Reasons) != 1 || summary.Reasons[0] != c.reason {
+				t.Fatalf("expected ineligible for %q, got %+v", c.reason, summary)
+			}
+		})
+	}
+}
+
+func TestGetApplicationProgressForEachStatus(t *testing.T) {
+	stub := newMockStub()
+	for status, expected := range statusProgress {
+		loanApplication := testApplication("LA-" + status)
+		loanApplication.Status = status
+		seedApplication(t, stub, loanApplication)
+
+		var progress applicationProgress
+		err := json.Unmarshal(stub.mustQuery(t, "GetApplicationProgress", loanApplication.ID), &progress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if progress.Status != status || progress.Progress != expected {
+			t.Errorf("expected %s at %d%%, got %+v", status, expected, progress)
+		}
+	}
+	if len(statusProgress) != len(validStatuses) {
+		t.Fatal("expected every status to have a progress percentage")
+	}
+
+	unknown := testApplication("LA-unknown")
+	unknown.Status = "Withdrawn"
+	seedApplication(t, stub, unknown)
+	if _, err := stub.query("GetApplicationProgress", "LA-unknown"); err == nil {
+		t.Fatal("expected an unknown status to be reported")
+	}
+}
+
+func TestGetLoanApplicationsByStatusPage(t *testing.T) {
+	stub := newMockStub()
+	for i, id := range []string{"LA-1", "LA-2", "LA-3", "LA-4", "LA-5", "LA-6"} {
+		loanApplication := testApplication(id)
+		if i%2 == 1 {
+			loanApplication.Status = statusApproved
+		}
+		seedApplication(t, stub, loanApplication)
+	}
+
+	var ids []string
+	bookmark := ""
+	pages := 0
+	for {
+		var page struct {
+			Records  []LoanApplication `json:"records"`
+			Bookmark string            `json:"bookmark"`
+		}
+		err := json.Unmarshal(stub.mustQuery(t, "GetLoanApplicationsByStatusPage", statusApproved, "2", bookmark), &page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, record := range page.Records {
+			if record.Status != statusApproved {
+				t.Fatalf("expected only Approved records, got %s", record.Status)
+			}
+			ids = append(ids, record.ID)
+		}
+		if page.Bookmark == "" {
+			break
+		}
+		bookmark = page.Bookmark
+	}
+	if pages != 2 {
+		t.Fatalf("expected 2 pages, got %d", pages)
+	}
+	assertIDs(t, ids, "LA-2", "LA-4", "LA-6")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// hasTag Report whether an application already carries a tag
+func hasTag(loanApplication LoanApplication, tag string) bool {
+	for _, t := range loanApplication.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag Tag application args[0] with args[1]
+func AddTag(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering AddTag")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID and tag")
+	}
+
+	var loanAppID = args[0]
+	var tag = strings.TrimSpace(args[1])
+
+	if tag == "" {
+		return nil, errors.New("Tag cannot be empty")
+	}
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	if hasTag(loanApplication, tag) {
+		return nil, errors.New("Loan application " + loanAppID + " is already tagged " + tag)
+	}
+
+	loanApplication.Tags = append(loanApplication.Tags, tag)
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	err = putIndexEntry(stub, tagIndexName, []string{tag, loanAppID})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully tagged loan application")
+	return nil, nil
+}
+
+// RemoveTag Remove tag args[1] from application args[0]
+func RemoveTag(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering RemoveTag")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID and tag")
+	}
+
+	var loanAppID = args[0]
+	var tag = strings.TrimSpace(args[1])
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	if !hasTag(loanApplication, tag) {
+		return nil, errors.New("Loan application " + loanAppID + " is not tagged " + tag)
+	}
+
+	tags := []string{}
+	for _, t := range loanApplication.Tags {
+		if t != tag {
+			tags = append(tags, t)
+		}
+	}
+	loanApplication.Tags = tags
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	err = delIndexEntry(stub, tagIndexName, []string{tag, loanAppID})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully untagged loan application")
+	return nil, nil
+}
+
+// GetLoanApplicationsByTag Get the applications tagged args[0]
+func GetLoanApplicationsByTag(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering GetLoanApplicationsByTag")
+
+	if len(args) < 1 || strings.TrimSpace(args[0]) == "" {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing tag")
+	}
+
+	loanApplications, err := getLoanApplicationsByIndex(stub, tagIndexName, strings.TrimSpace(args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalLoanApplications(stub, loanApplications)
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestTagLookupFollowsTagging(t *testing.T) {
+	stub := newMockStub()
+	for _, id := range []string{"LA-1", "LA-2", "LA-3"} {
+		seedApplication(t, stub, testApplication(id))
+	}
+
+	stub.mustInvoke(t, "AddTag", "LA-1", "fast-track")
+	stub.mustInvoke(t, "AddTag", "LA-2", " fast-track ")
+	stub.mustInvoke(t, "AddTag", "LA-2", "fraud-watch")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByTag", "fast-track")), "LA-1", "LA-2")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByTag", "fraud-watch")), "LA-2")
+	if tags := storedApplication(t, stub, "LA-2").Tags; len(tags) != 2 || tags[0] != "fast-track" || tags[1] != "fraud-watch" {
+		t.Fatalf("expected LA-2 to carry both tags, got %v", tags)
+	}
+
+	stub.mustInvoke(t, "RemoveTag", "LA-2", "fast-track")
+	assertIDs(t, recordIDs(t, stub.mustQuery(t, "GetLoanApplicationsByTag", "fast-track")), "LA-1")
+	if tags := storedApplication(t, stub, "LA-2").Tags; len(tags) != 1 || tags[0] != "fraud-watch" {
+		t.Fatalf("expected only fraud-watch left on LA-2, got %v", tags)
+	}
+}
+
+func TestTaggingRejectsDuplicatesAndUnknownTags(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+	stub.mustInvoke(t, "AddTag", "LA-1", "fast-track")
+
+	if _, err := stub.invoke("AddTag", "LA-1", "fast-track"); err == nil {
+		t.Fatal("expected a duplicate tag to be rejected")
+	}
+	if _, err := stub.invoke("AddTag", "LA-1", "  "); err == nil {
+		t.Fatal("expected an empty tag to be rejected")
+	}
+	if _, err := stub.invoke("RemoveTag", "LA-1", "fraud-watch"); err == nil {
+		t.Fatal("expected removing an absent tag to be rejected")
+	}
+	if tags := storedApplication(t, stub, "LA-1").Tags; len(tags) != 1 {
+		t.Fatalf("expected a single tag, got %v", tags)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// dateLayout Layout expected for date-only fields such as DOB
+const dateLayout = "2006-01-02"
+
+// validCurrencies ISO 4217 codes loan amounts may be denominated in
+var validCurrencies = map[string]bool{
+	"AUD": true,
+	"CAD": true,
+	"CHF": true,
+	"EUR": true,
+	"GBP": true,
+	"JPY": true,
+	"NZD": true,
+	"SGD": true,
+	"USD": true,
+}
+
+// validationRules Checks applied for each rule name used in validate struct tags
+var validationRules = map[string]func(v reflect.Value, param string) string{
+	"required": func(v reflect.Value, param string) string {
+		if v.IsZero() {
+			return "is required"
+		}
+		return ""
+	},
+	"email": func(v reflect.Value, param string) string {
+		s := v.String()
+		if s == "" {
+			return ""
+		}
+		at := strings.Index(s, "@")
+		if at < 1 || at != strings.LastIndex(s, "@") || !strings.Contains(s[at+1:], ".") ||
+			strings.HasSuffix(s, ".") || strings.ContainsAny(s, " \t") {
+			return "must be a valid email address"
+		}
+		return ""
+	},
+	"date": func(v reflect.Value, param string) string {
+		s := v.String()
+		if s == "" {
+			return ""
+		}
+		if _, err := time.Parse(dateLayout, s); err != nil {
+			return "must be a date in YYYY-MM-DD format"
+		}
+		return ""
+	},
+	"currency": func(v reflect.Value, param string) string {
+		s := v.String()
+		if s != "" && !validCurrencies[s] {
+			return "must be a supported ISO currency code"
+		}
+		return ""
+	},
+	"phone": func(v reflect.Value, param string) string {
+		s := strings.TrimPrefix(v.String(), "+")
+		if s == "" {
+			return ""
+		}
+		for _, c := range s {
+			if c < '0' || c > '9' {
+				return "must contain only digits and an optional leading +"
+			}
+		}
+		return ""
+	},
+	"min": func(v reflect.Value, param string) string {
+		min, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return "has invalid min rule " + param
+		}
+		if v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64 && v.Int() < min {
+			return "must be at least " + param
+		}
+		return ""
+	},
+}
+
+// validateStruct Apply the validate tags of a struct and its nested structs, collecting every violation
+func validateStruct(v interface{}) []string {
+	return validateValue(reflect.ValueOf(v), "")
+}
+
+func validateValue(v reflect.Value, path string) []string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var violations []string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if rule == "" {
+				continue
+			}
+			ruleName, param := rule, ""
+			if eq := strings.Index(rule, "="); eq >= 0 {
+				ruleName, param = rule[:eq], rule[eq+1:]
+			}
+			check, ok := validationRules[ruleName]
+			if !ok {
+				violations = append(violations, fmt.Sprintf("%s has unknown validation rule %s", name, ruleName))
+				continue
+			}
+			if msg := check(value, param); msg != "" {
+				violations = append(violations, name+" "+msg)
+			}
+		}
+
+		if value.Kind() == reflect.Struct {
+			violations = append(violations, validateValue(value, name)...)
+		}
+	}
+	return violations
+}
+
+// validateMonetary Ensure monetary totals derived from an application do not overflow
+func validateMonetary(loanApplication LoanApplication) []string {
+	if _, err := monthlyOutgoings(loanApplication.FinancialInfo); err != nil {
+		return []string{"financialInfo monthly outgoings overflow"}
+	}
+	return nil
+}
+
+// validateLoanApplication Collect every violation of the current field, monetary and product rules
+func validateLoanApplication(stub shim.ChaincodeStubInterface, loanApplication LoanApplication) ([]string, error) {
+	violations := append(validateStruct(&loanApplication), validateMonetary(loanApplication)...)
+	productViolations, err := validateProduct(stub, loanApplication)
+	if err != nil {
+		return nil, err
+	}
+	return append(violations, productViolations...), nil
+}
+
+// createResponse Returned from a create that generated the application ID or matches an existing applicant
+type createResponse struct {
+	ID         string `json:"id,omitempty"`
+	Warning    string `json:"warning,omitempty"`
+	ExistingID string `json:"existingId,omitempty"`
+}
+
+// findDuplicateApplication Find another application with the same applicant email, DOB and mobile
+func findDuplicateApplication(stub shim.ChaincodeStubInterface, loanApplication LoanApplication) (string, error) {
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return "", err
+	}
+
+	info := loanApplication.PersonalInfo
+	for _, existing := range loanApplications {
+		if existing.ID == loanApplication.ID {
+			continue
+		}
+		if strings.EqualFold(existing.PersonalInfo.Email, info.Email) &&
+			existing.PersonalInfo.DOB == info.DOB &&
+			existing.PersonalInfo.Mobile == info.Mobile {
+			return existing.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// revalidationResult The rules an existing application no longer satisfies
+type revalidationResult struct {
+	ID         string   `json:"id"`
+	Violations []string `json:"violations"`
+}
+
+// RevalidateAll Run the current validation rules over every stored application without changing any,
+// returning those that would now fail
+func RevalidateAll(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering RevalidateAll")
+
+	loanApplications, err := getAllLoanApplications(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []revalidationResult{}
+	for _, loanApplication := range loanApplications {
+		violations, err := validateLoanApplication(stub, loanApplication)
+		if err != nil {
+			return nil, err
+		}
+		// Purged personal information is blank by design, not a violation
+		if loanApplication.PIIPurged {
+			kept := []string{}
+			for _, violation := range violations {
+				if !strings.HasPrefix(violation, "personalInfo.") {
+					kept = append(kept, violation)
+				}
+			}
+			violations = kept
+		}
+		if len(violations) > 0 {
+			results = append(results, revalidationResult{ID: loanApplication.ID, Violations: violations})
+		}
+	}
+
+	bytes, err := json.Marshal(results)
+	if err != nil {
+		logger.Error("Could not marshal revalidation results", err)
+		return nil, err
+	}
+	return bytes, nil
+}
+
+// checkPropertyCapacity Ensure another application may be opened against a property without
+// exceeding the configured cap on applications still in progress
+func checkPropertyCapacity(stub shim.ChaincodeStubInterface, propertyID string, params LoanParameters) error {
+	if propertyID == "" || params.MaxOpenApplicationsPerProperty <= 0 {
+		return nil
+	}
+	loanApplications, err := getLoanApplicationsByIndex(stub, propertyIndexName, propertyID)
+	if err != nil {
+		return err
+	}
+	open := 0
+	for _, loanApplication := range loanApplications {
+		if !terminalStatuses[loanApplication.Status] {
+			open++
+		}
+	}
+	if open >= params.MaxOpenApplicationsPerProperty {
+		logger.Error("Property " + propertyID + " already has " + strconv.Itoa(open) + " open loan applications")
+		return errors.New("Property " + propertyID + " already has the maximum of " + strconv.Itoa(params.MaxOpenApplicationsPerProperty) + " open loan applications")
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCreateLoanApplicationValidatesTags(t *testing.T) {
+	cases := map[string]struct {
+		modify    func(loanApplication *LoanApplication)
+		violation string
+	}{
+		"required": {func(la *LoanApplication) { la.PersonalInfo.Firstname = "" }, "personalInfo.firstname is required"},
+		"email":    {func(la *LoanApplication) { la.PersonalInfo.Email = "jane@example" }, "personalInfo.email must be a valid email address"},
+		"date":     {func(la *LoanApplication) { la.PersonalInfo.DOB = "01/06/1985" }, "personalInfo.DOB must be a date in YYYY-MM-DD format"},
+		"phone":    {func(la *LoanApplication) { la.PersonalInfo.Mobile = "0400-000" }, "personalInfo.mobile must contain only digits"},
+		"min":      {func(la *LoanApplication) { la.FinancialInfo.MonthlyRent = -1 }, "financialInfo.monthlyRent must be at least 0"},
+		"currency": {func(la *LoanApplication) { la.Currency = "XYZ" }, "currency must be a supported ISO currency code"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			stub := newMockStub()
+			loanApplication := testApplication("LA-1")
+			c.modify(&loanApplication)
+			input, _ := json.Marshal(loanApplication)
+
+			_, err := stub.invoke("CreateLoanApplication", "LA-1", string(input))
+			if err == nil || !strings.Contains(err.Error(), c.violation) {
+				t.Fatalf("expected violation %q, got %v", c.violation, err)
+			}
+			if stub.state[loanKey("LA-1")] != nil {
+				t.Fatal("expected nothing to be written")
+			}
+		})
+	}
+}
+
+func TestCreateLoanApplicationReportsEveryViolation(t *testing.T) {
+	stub := newMockStub()
+	loanApplication := testApplication("LA-1")
+	loanApplication.PersonalInfo.Lastname = ""
+	loanApplication.PersonalInfo.Email = "not an email"
+	input, _ := json.Marshal(loanApplication)
+
+	_, err := stub.invoke("CreateLoanApplication", "LA-1", string(input))
+	if err == nil || !strings.Contains(err.Error(), "personalInfo.lastname is required; personalInfo.email must be a valid email address") {
+		t.Fatalf("expected both violations, got %v", err)
+	}
+}
+
+func TestCreateLoanApplicationWarnsOfDuplicate(t *testing.T) {
+	stub := newMockStub()
+	createApplication(t, stub, testApplication("LA-1"))
+
+	duplicate := testApplication("LA-2")
+	duplicate.PersonalInfo = testApplication("LA-1").PersonalInfo
+	duplicate.PersonalInfo.Email = "LA-1@EXAMPLE.com"
+
+	var response createResponse
+	err := json.Unmarshal(createApplication(t, stub, duplicate), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Warning != "possible duplicate" || response.ExistingID != "LA-1" {
+		t.Fatalf("expected a duplicate warning naming LA-1, got %+v", response)
+	}
+	storedApplication(t, stub, "LA-2")
+}
+
+func TestCreateLoanApplicationRejectsDuplicate(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"rejectDuplicates":true}`)
+	createApplication(t, stub, testApplication("LA-1"))
+
+	duplicate := testApplication("LA-2")
+	duplicate.PersonalInfo = testApplication("LA-1").PersonalInfo
+	input, _ := json.Marshal(duplicate)
+
+	_, err := stub.invoke("CreateLoanApplication", "LA-2", string(input))
+	if err == nil || !strings.Contains(err.Error(), "duplicate existing application LA-1") {
+		t.Fatalf("expected the duplicate to be rejected, got %v", err)
+	}
+	if stub.state[loanKey("LA-2")] != nil {
+		t.Fatal("expected the duplicate not to be saved")
+	}
+
+	// A different mobile number is a different applicant
+	distinct := testApplication("LA-3")
+	distinct.PersonalInfo = testApplication("LA-1").PersonalInfo
+	distinct.PersonalInfo.Mobile = "+61499999999"
+	if result := createApplication(t, stub, distinct); result != nil {
+		t.Fatalf("expected no warning, got %s", result)
+	}
+}
+
+func TestRevalidateAllFlagsRecordsFailingTightenedRules(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetProductSchemas", `{"construction":["PermitID"]}`)
+	for _, id := range []string{"LA-1", "LA-2", "LA-3"} {
+		loanApplication := testApplication(id)
+		loanApplication.ProductType = "construction"
+		if id == "LA-2" {
+			loanApplication.SalesContractID = "CONTRACT-2"
+		}
+		createApplication(t, stub, loanApplication)
+	}
+
+	var results []revalidationResult
+	err := json.Unmarshal(stub.mustQuery(t, "RevalidateAll"), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected every record to pass the rules it was created under, got %+v", results)
+	}
+
+	stub.mustInvoke(t, "SetProductSchemas", `{"construction":["PermitID","SalesContractID"]}`)
+	before := storedApplication(t, stub, "LA-1")
+	err = json.Unmarshal(stub.mustQuery(t, "RevalidateAll"), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != "LA-1" || results[1].ID != "LA-3" {
+		t.Fatalf("expected LA-1 and LA-3 to be flagged, got %+v", results)
+	}
+	if len(results[0].Violations) != 1 || results[0].Violations[0] != "SalesContractID is required for product construction" {
+		t.Fatalf("unexpected violations %v", results[0].Violations)
+	}
+	if after := storedApplication(t, stub, "LA-1"); after.LastModifiedDate != before.LastModifiedDate || after.Status != before.Status {
+		t.Fatal("expected revalidation not to change any record")
+	}
+}
+
+func TestCreateLoanApplicationEnforcesPropertyCap(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"maxOpenApplicationsPerProperty":2}`)
+	onProperty := func(id string) LoanApplication {
+		loanApplication := testApplication(id)
+		loanApplication.PropertyID = "PROP-1"
+		return loanApplication
+	}
+	createApplication(t, stub, onProperty("LA-1"))
+	createApplication(t, stub, onProperty("LA-2"))
+
+	input, _ := json.Marshal(onProperty("LA-3"))
+	_, err := stub.invoke("CreateLoanApplication", "LA-3", string(input))
+	if err == nil || !strings.Contains(err.Error(), "Property PROP-1 already has the maximum of 2 open loan applications") {
+		t.Fatalf("expected the third application on the property to be rejected, got %v", err)
+	}
+	if stub.state[loanKey("LA-3")] != nil {
+		t.Fatal("expected nothing to be written")
+	}
+
+	// Other properties are unaffected and a terminal application frees a slot
+	createApplication(t, stub, testApplication("LA-4"))
+	rejected := storedApplication(t, stub, "LA-1")
+	rejected.Status = statusRejected
+	seedApplication(t, stub, rejected)
+	createApplication(t, stub, onProperty("LA-3"))
+}
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// systemReviewerID Reviewer recorded on applications approved without human review
+const systemReviewerID = "system"
+
+// ApprovalCondition A condition that must be met before a conditional approval becomes final
+type ApprovalCondition struct {
+	Description   string `json:"description"`
+	Satisfied     bool   `json:"satisfied"`
+	SatisfiedBy   string `json:"satisfiedBy,omitempty"`
+	SatisfiedDate string `json:"satisfiedDate,omitempty"`
+}
+
+// setStatus Move an application to a new status and record the change in its status history,
+// starting the approval validity period when it becomes Approved
+func setStatus(stub shim.ChaincodeStubInterface, loanApplication *LoanApplication, status string, note string) error {
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return err
+	}
+	username, _ := GetCertAttribute(stub, "username")
+
+	if status == statusApproved && loanApplication.Status != statusApproved {
+		params, err := getLoanParameters(stub)
+		if err != nil {
+			return err
+		}
+		loanApplication.ApprovalExpiryDate = ""
+		if params.ApprovalValidityDays > 0 {
+			loanApplication.ApprovalExpiryDate = now.AddDate(0, 0, params.ApprovalValidityDays).Format(time.RFC3339)
+		}
+	}
+	loanApplication.Status = status
+	loanApplication.StatusHistory = append(loanApplication.StatusHistory, StatusChange{
+		Status:     status,
+		Date:       now.Format(time.RFC3339),
+		ChangedBy:  username,
+		ReviewerID: loanApplication.ReviewerID,
+		Note:       note,
+	})
+	return nil
+}
+
+// ownStatusHistory The status changes an application went through itself, leaving out those carried over
+// from duplicates merged into it, for replaying its status over time
+func ownStatusHistory(loanApplication LoanApplication) []StatusChange {
+	own := []StatusChange{}
+	for _, change := range loanApplication.StatusHistory {
+		if change.MergedFrom == "" {
+			own = append(own, change)
+		}
+	}
+	return own
+}
+
+// routeNewApplication Auto-approve small applications that pass every automated check,
+// leaving everything else Submitted for human review
+func routeNewApplication(stub shim.ChaincodeStubInterface, loanApplication *LoanApplication, params LoanParameters, now time.Time) error {
+	loanApplication.ReviewerID = ""
+	loanApplication.StatusHistory = nil
+	err := setStatus(stub, loanApplication, statusSubmitted, "submitted")
+	if err != nil {
+		return err
+	}
+
+	if params.AutoApproveThreshold <= 0 || loanApplication.RequestedAmount >= params.AutoApproveThreshold {
+		return nil
+	}
+	if reasons := eligibilityReasons(*loanApplication, params, now); len(reasons) > 0 {
+		logger.Debug("Loan application " + loanApplication.ID + " not auto-approved: " + strings.Join(reasons, "; "))
+		return nil
+	}
+	rates, err := getCreditTierRates(stub)
+	if err != nil {
+		return err
+	}
+	rate, ok := rates[loanApplication.CreditTier]
+	if !ok {
+		return nil
+	}
+	if err := checkLoanTerms(loanApplication.TermMonths, rate, params); err != nil {
+		logger.Debug("Loan application " + loanApplication.ID + " not auto-approved: " + err.Error())
+		return nil
+	}
+	if loanApplication.RequestedAmount < params.MinApprovedAmount {
+		logger.Debug("Loan application " + loanApplication.ID + " not auto-approved: below minimum approved amount")
+		return nil
+	}
+
+	loanApplication.ReviewerID = systemReviewerID
+	loanApplication.ApprovedAmount = loanApplication.RequestedAmount
+	loanApplication.InterestRateBps = rate
+	return setStatus(stub, loanApplication, statusApproved, "auto-approved below threshold")
+}
+
+// checkLoanTerms Ensure a term and interest rate in basis points fall within the configured product bounds,
+// treating unset bounds as unlimited
+func checkLoanTerms(termMonths int, rateBps int, params LoanParameters) error {
+	if params.MinTermMonths > 0 && termMonths < params.MinTermMonths {
+		return errors.New("Term of " + strconv.Itoa(termMonths) + " months is below the minimum of " + strconv.Itoa(params.MinTermMonths))
+	}
+	if params.MaxTermMonths > 0 && termMonths > params.MaxTermMonths {
+		return errors.New("Term of " + strconv.Itoa(termMonths) + " months exceeds the maximum of " + strconv.Itoa(params.MaxTermMonths))
+	}
+	if params.MinInterestRateBps > 0 && rateBps < params.MinInterestRateBps {
+		return errors.New("Interest rate " + formatRateBps(rateBps) + "% is below the minimum of " + formatRateBps(params.MinInterestRateBps) + "%")
+	}
+	if params.MaxInterestRateBps > 0 && rateBps > params.MaxInterestRateBps {
+		return errors.New("Interest rate " + formatRateBps(rateBps) + "% exceeds the maximum of " + formatRateBps(params.MaxInterestRateBps) + "%")
+	}
+	return nil
+}
+
+// missingDocumentTypes List the required document types not attached to an application
+func missingDocumentTypes(loanApplication LoanApplication, required []string) []string {
+	attached := map[string]bool{}
+	for _, document := range loanApplication.Documents {
+		attached[document.Type] = true
+	}
+
+	var missing []string
+	for _, documentType := range required {
+		if !attached[documentType] {
+			missing = append(missing, documentType)
+		}
+	}
+	return missing
+}
+
+// approvalDecision Run every approval check on application args[0] with optional amount args[1] and
+// decision code args[2], consuming replay nonce args[3], and return it with the approved terms set
+func approvalDecision(stub shim.ChaincodeStubInterface, args []string) (LoanApplication, error) {
+	var loanAppID = args[0]
+
+	err := consumeNonce(stub, loanAppID, nonceOpApprove, args[3])
+	if err != nil {
+		return LoanApplication{}, err
+	}
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return LoanApplication{}, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return LoanApplication{}, err
+	}
+	if loanApplication.Status != statusUnderReview {
+		return LoanApplication{}, errors.New("Loan application " + loanAppID + " is " + loanApplication.Status + " and cannot be approved")
+	}
+
+	approvedAmount := loanApplication.RequestedAmount
+	if args[1] != "" {
+		approvedAmount, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil || approvedAmount <= 0 {
+			return LoanApplication{}, errors.New("Approved amount must be a positive integer")
+		}
+	}
+
+	var decisionCode string
+	if args[2] != "" {
+		decisionCode = args[2]
+		err = validateDecisionCode(stub, decisionCode)
+		if err != nil {
+			return LoanApplication{}, err
+		}
+	}
+
+	params, err := getLoanParameters(stub)
+	if err != nil {
+		return LoanApplication{}, err
+	}
+	if missing := missingDocumentTypes(loanApplication, params.RequiredDocumentTypes); len(missing) > 0 {
+		return LoanApplication{}, errors.New("Loan application " + loanAppID + " is missing required documents: " + strings.Join(missing, ", "))
+	}
+
+	rates, err := getCreditTierRates(stub)
+	if err != nil {
+		return LoanApplication{}, err
+	}
+	rate, ok := rates[loanApplication.CreditTier]
+	if !ok {
+		return LoanApplication{}, errors.New("Unknown credit tier '" + loanApplication.CreditTier + "' for loan application " + loanAppID)
+	}
+	err = checkLoanTerms(loanApplication.TermMonths, rate, params)
+	if err != nil {
+		return LoanApplication{}, errors.New("Loan application " + loanAppID + ": " + err.Error())
+	}
+	if params.MinApprovedAmount > 0 && approvedAmount < params.MinApprovedAmount {
+		return LoanApplication{}, errors.New("Approved amount " + strconv.FormatInt(approvedAmount, 10) + " for loan application " + loanAppID +
+			" is below the minimum of " + strconv.FormatInt(params.MinApprovedAmount, 10))
+	}
+
+	loanApplication.ApprovedAmount = approvedAmount
+	loanApplication.InterestRateBps = rate
+	loanApplication.DecisionCode = decisionCode
+	return loanApplication, nil
+}
+
+// ApproveLoanApplication Approve application args[0], optionally for amount args[1] with decision code args[2],
+// using replay nonce args[3]
+func ApproveLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering ApproveLoanApplication")
+
+	if len(args) < 4 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID, approved amount, decision code and nonce")
+	}
+
+	var loanAppID = args[0]
+
+	loanApplication, err := approvalDecision(stub, args)
+	if err != nil {
+		return nil, err
+	}
+	err = setStatus(stub, &loanApplication, statusApproved, loanApplication.DecisionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "loanApplicationApproval", loanAppID+" Successfully approved", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully approved loan application")
+	return nil, nil
+}
+
+// ApproveWithConditions Approve application args[0] subject to the JSON array of conditions args[4],
+// with amount args[1], decision code args[2] and replay nonce args[3] as for ApproveLoanApplication
+func ApproveWithConditions(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering ApproveWithConditions")
+
+	if len(args) < 5 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID, approved amount, decision code, nonce and conditions")
+	}
+
+	var loanAppID = args[0]
+
+	var descriptions []string
+	err := json.Unmarshal([]byte(args[4]), &descriptions)
+	if err != nil {
+		logger.Error("Could not unmarshal approval conditions", err)
+		return nil, errors.New("Invalid approval conditions: " + err.Error())
+	}
+	if len(descriptions) == 0 {
+		return nil, errors.New("At least one approval condition is required")
+	}
+	conditions := []ApprovalCondition{}
+	for _, description := range descriptions {
+		if strings.TrimSpace(description) == "" {
+			return nil, errors.New("Approval conditions cannot be empty")
+		}
+		conditions = append(conditions, ApprovalCondition{Description: description})
+	}
+
+	loanApplication, err := approvalDecision(stub, args)
+	if err != nil {
+		return nil, err
+	}
+	loanApplication.Conditions = conditions
+	err = setStatus(stub, &loanApplication, statusConditionallyApproved, loanApplication.DecisionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "loanApplicationConditionalApproval", loanAppID+" approved with "+strconv.Itoa(len(conditions))+" conditions", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully conditionally approved loan application")
+	return nil, nil
+}
+
+// SatisfyCondition Mark condition number args[1], counting from zero, of conditionally approved application
+// args[0] as met, approving the application once every condition is met
+func SatisfyCondition(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering SatisfyCondition")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID and condition index")
+	}
+
+	var loanAppID = args[0]
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	if loanApplication.Status != statusConditionallyApproved {
+		return nil, errors.New("Loan application " + loanAppID + " is " + loanApplication.Status + " and has no outstanding conditions")
+	}
+	index, err := strconv.Atoi(args[1])
+	if err != nil || index < 0 || index >= len(loanApplication.Conditions) {
+		return nil, errors.New("Condition index must be between 0 and " + strconv.Itoa(len(loanApplication.Conditions)-1))
+	}
+	if loanApplication.Conditions[index].Satisfied {
+		return nil, errors.New("Condition " + args[1] + " of loan application " + loanAppID + " is already satisfied")
+	}
+
+	now, err := txTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	username, _ := GetCertAttribute(stub, "username")
+
+	loanApplication.Conditions[index].Satisfied = true
+	loanApplication.Conditions[index].SatisfiedBy = username
+	loanApplication.Conditions[index].SatisfiedDate = now.Format(time.RFC3339)
+
+	outstanding := 0
+	for _, condition := range loanApplication.Conditions {
+		if !condition.Satisfied {
+			outstanding++
+		}
+	}
+	eventType := "approvalConditionSatisfied"
+	description := loanAppID + " condition " + args[1] + " satisfied"
+	if outstanding == 0 {
+		err = setStatus(stub, &loanApplication, statusApproved, "all conditions satisfied")
+		if err != nil {
+			return nil, err
+		}
+		eventType = "loanApplicationApproval"
+		description = loanAppID + " Successfully approved"
+	}
+
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, eventType, description, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully satisfied approval condition")
+	return nil, nil
+}
+
+// RejectLoanApplication Reject application args[0] with decision code args[1] and reason args[2]
+func RejectLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering RejectLoanApplication")
+
+	if len(args) < 3 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID, decision code and rejection reason")
+	}
+
+	var loanAppID = args[0]
+	var decisionCode = args[1]
+	var reason = args[2]
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	if loanApplication.Status != statusUnderReview {
+		return nil, errors.New("Loan application " + loanAppID + " is " + loanApplication.Status + " and cannot be rejected")
+	}
+	err = validateDecisionCode(stub, decisionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	loanApplication.DecisionCode = decisionCode
+	loanApplication.RejectionReason = reason
+	err = setStatus(stub, &loanApplication, statusRejected, decisionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "loanApplicationRejection", loanAppID+" rejected", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully rejected loan application")
+	return nil, nil
+}
+
+// ArchiveLoanApplication Archive finished application args[0] and request it be copied to cold storage
+func ArchiveLoanApplication(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering ArchiveLoanApplication")
+
+	if len(args) < 1 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Missing loan application ID")
+	}
+
+	var loanAppID = args[0]
+
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	if loanApplication.Status != statusRejected && loanApplication.Status != statusDisbursed && loanApplication.Status != statusMerged {
+		return nil, errors.New("Loan application " + loanAppID + " is " + loanApplication.Status + " and cannot be archived")
+	}
+
+	err = setStatus(stub, &loanApplication, statusArchived, "archived")
+	if err != nil {
+		return nil, err
+	}
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	// The full record lets an off-chain listener persist it before it is purged
+	err = sendEvent(stub, "archiveRequested", loanAppID+" archived", &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully archived loan application")
+	return nil, nil
+}
+
+// TransferApplicationBuyer Move application args[0] to buyer args[1]
+func TransferApplicationBuyer(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering TransferApplicationBuyer")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected loan application ID and new buyer ID")
+	}
+
+	var loanAppID = args[0]
+	var buyerID = args[1]
+
+	if buyerID == "" {
+		return nil, errors.New("New buyer ID cannot be empty")
+	}
+	loanApplication, err := getLoanApplication(stub, loanAppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureUnlocked(loanApplication); err != nil {
+		return nil, err
+	}
+	if loanApplication.Status == statusDisbursed {
+		return nil, errors.New("Loan application " + loanAppID + " has been disbursed and cannot change buyer")
+	}
+	if loanApplication.BuyerID == buyerID {
+		return nil, errors.New("Loan application " + loanAppID + " already belongs to buyer " + buyerID)
+	}
+
+	previousBuyerID := loanApplication.BuyerID
+	if previousBuyerID != "" {
+		err = delIndexEntry(stub, buyerIndexName, []string{previousBuyerID, loanAppID})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = putIndexEntry(stub, buyerIndexName, []string{buyerID, loanAppID})
+	if err != nil {
+		return nil, err
+	}
+
+	loanApplication.BuyerID = buyerID
+	err = setStatus(stub, &loanApplication, loanApplication.Status, "buyer transferred from "+previousBuyerID+" to "+buyerID)
+	if err != nil {
+		return nil, err
+	}
+	err = saveLoanApplication(stub, &loanApplication)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sendEvent(stub, "buyerTransfer", loanAppID+" transferred to buyer "+buyerID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully transferred loan application buyer")
+	return nil, nil
+}
+
+// batchResult Outcome of one application in a batch operation
+type batchResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BatchApprove Approve each application in the JSON array of IDs args[0] using replay nonce args[1],
+// reporting per application instead of stopping at the first failure
+func BatchApprove(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	logger.Debug("Entering BatchApprove")
+
+	if len(args) < 2 {
+		logger.Error("Invalid number of args")
+		return nil, errors.New("Expected JSON array of loan application IDs and nonce")
+	}
+
+	var loanAppIDs []string
+	err := json.Unmarshal([]byte(args[0]), &loanAppIDs)
+	if err != nil {
+		logger.Error("Could not unmarshal loan application IDs", err)
+		return nil, errors.New("Invalid loan application IDs: " + err.Error())
+	}
+	var nonce = args[1]
+
+	results := []batchResult{}
+	approved := 0
+	for _, loanAppID := range loanAppIDs {
+		// Each approval is staged on its own so a failure leaves no partial writes, such as a consumed nonce
+		staged := newStagedStub(stub)
+		_, err := ApproveLoanApplication(staged, []string{loanAppID, "", "", nonce})
+		if err == nil {
+			err = staged.flush()
+		}
+		if err != nil {
+			logger.Warning("Could not approve loan application " + loanAppID + ": " + err.Error())
+			results = append(results, batchResult{ID: loanAppID, Error: err.Error()})
+			continue
+		}
+		approved++
+		results = append(results, batchResult{ID: loanAppID, Success: true})
+	}
+
+	err = sendSummaryEvent(stub, "batchApproval", strconv.Itoa(approved)+" of "+strconv.Itoa(len(loanAppIDs))+" loan applications approved", results)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Successfully processed batch approval")
+	return json.Marshal(results)
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// seedUnderReview Seed an application under review by rev1
+func seedUnderReview(t *testing.T, stub *mockStub, loanApplication LoanApplication) {
+	t.Helper()
+	loanApplication.Status = statusUnderReview
+	loanApplication.ReviewerID = "rev1"
+	seedApplication(t, stub, loanApplication)
+}
+
+func TestApproveUsesCreditTierRate(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5.25,"B":7.5}`)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+
+	stub.as("rev1", roleReviewer).mustInvoke(t, "ApproveLoanApplication", "LA-1", "", "", "n1")
+
+	loanApplication := storedApplication(t, stub, "LA-1")
+	if loanApplication.Status != statusApproved || loanApplication.InterestRateBps != 525 {
+		t.Fatalf("expected approval at 525 bps, got %s at %d", loanApplication.Status, loanApplication.InterestRateBps)
+	}
+	if rates := string(stub.mustQuery(t, "GetCreditTierRates")); rates != `{"A":5.25,"B":7.5}` {
+		t.Fatalf("expected rates as percentages, got %s", rates)
+	}
+}
+
+func TestApproveRejectsUnknownCreditTier(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5.25}`)
+	loanApplication := testApplication("LA-1")
+	loanApplication.CreditTier = "Z"
+	seedUnderReview(t, stub, loanApplication)
+
+	_, err := stub.as("rev1", roleReviewer).invoke("ApproveLoanApplication", "LA-1", "", "", "n1")
+	if err == nil || !strings.Contains(err.Error(), "Unknown credit tier 'Z'") {
+		t.Fatalf("expected an unknown credit tier error, got %v", err)
+	}
+	if storedApplication(t, stub, "LA-1").Status != statusUnderReview {
+		t.Fatal("expected the application to stay under review")
+	}
+}
+
+func TestApproveRequiresDocumentChecklist(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	stub.mustInvoke(t, "SetLoanParameters", `{"requiredDocumentTypes":["payslip","valuation"]}`)
+
+	incomplete := testApplication("LA-1")
+	incomplete.Documents = []Document{{Type: "payslip", Reference: "doc-1"}}
+	seedUnderReview(t, stub, incomplete)
+	complete := testApplication("LA-2")
+	complete.Documents = []Document{{Type: "valuation", Reference: "doc-2"}, {Type: "payslip", Reference: "doc-3"}}
+	seedUnderReview(t, stub, complete)
+
+	stub.as("rev1", roleReviewer)
+	_, err := stub.invoke("ApproveLoanApplication", "LA-1", "", "", "n1")
+	if err == nil || !strings.Contains(err.Error(), "missing required documents: valuation") {
+		t.Fatalf("expected the missing valuation to block approval, got %v", err)
+	}
+	stub.mustInvoke(t, "ApproveLoanApplication", "LA-2", "", "", "n2")
+	if storedApplication(t, stub, "LA-2").Status != statusApproved {
+		t.Fatal("expected the complete application to be approved")
+	}
+}
+
+func TestArchiveEmitsFullRecord(t *testing.T) {
+	stub := newMockStub()
+	rejected := testApplication("LA-1")
+	rejected.Status = statusRejected
+	seedApplication(t, stub, rejected)
+
+	stub.mustInvoke(t, "ArchiveLoanApplication", "LA-1")
+
+	event := stub.lastEvent(t)
+	var payload struct {
+		Type    string          `json:"type"`
+		Payload LoanApplication `json:"payload"`
+	}
+	err := json.Unmarshal(event.payload, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != "archiveRequested" || payload.Payload.ID != "LA-1" || payload.Payload.Status != statusArchived {
+		t.Fatalf("expected an archiveRequested event carrying archived LA-1, got %s", event.payload)
+	}
+	if payload.Payload.PersonalInfo.Lastname != "Doe-LA-1" {
+		t.Fatal("expected the event to carry the full record")
+	}
+}
+
+func TestArchiveRequiresFinishedApplication(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	if _, err := stub.invoke("ArchiveLoanApplication", "LA-1"); err == nil {
+		t.Fatal("expected a submitted application not to be archived")
+	}
+	if len(stub.events) != 0 {
+		t.Fatal("expected no event")
+	}
+}
+
+func TestCreateAutoApprovesSmallLoans(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	stub.mustInvoke(t, "SetLoanParameters", `{"autoApproveThreshold":100000,"maxLoanToValue":0.8}`)
+
+	small := testApplication("LA-1")
+	small.RequestedAmount = 50000
+	createApplication(t, stub, small)
+	large := testApplication("LA-2")
+	large.RequestedAmount = 100000
+	createApplication(t, stub, large)
+	// Small but failing an automated check, so left for a reviewer
+	risky := testApplication("LA-3")
+	risky.RequestedAmount = 50000
+	risky.FairMarketValue = 60000
+	createApplication(t, stub, risky)
+
+	approved := storedApplication(t, stub, "LA-1")
+	if approved.Status != statusApproved || approved.ReviewerID != systemReviewerID || approved.ApprovedAmount != 50000 || approved.InterestRateBps != 500 {
+		t.Fatalf("expected LA-1 auto-approved for 50000 at 500 bps, got %+v", approved)
+	}
+	for _, id := range []string{"LA-2", "LA-3"} {
+		if loanApplication := storedApplication(t, stub, id); loanApplication.Status != statusSubmitted || loanApplication.ReviewerID != "" {
+			t.Errorf("expected %s routed for review, got %s", id, loanApplication.Status)
+		}
+	}
+}
+
+func TestTransferApplicationBuyerMovesIndexEntry(t *testing.T) {
+	stub := newMockStub()
+	seedApplication(t, stub, testApplication("LA-1"))
+
+	stub.mustInvoke(t, "TransferApplicationBuyer", "LA-1", "BUYER-2")
+
+	if keys, _ := getKeysByPartialCompositeKey(stub, buyerIndexName, []string{"BUYER-LA-1"}); len(keys) != 0 {
+		t.Fatal("expected the previous buyer's index entry to be removed")
+	}
+	keys, _ := getKeysByPartialCompositeKey(stub, buyerIndexName, []string{"BUYER-2"})
+	if len(keys) != 1 {
+		t.Fatalf("expected one index entry for the new buyer, got %d", len(keys))
+	}
+	if _, attributes := splitCompositeKey(keys[0]); attributes[1] != "LA-1" {
+		t.Fatalf("expected the new buyer entry to point at LA-1, got %v", attributes)
+	}
+	loanApplication := storedApplication(t, stub, "LA-1")
+	if loanApplication.BuyerID != "BUYER-2" || loanApplication.Status != statusSubmitted {
+		t.Fatalf("expected LA-1 to belong to BUYER-2 and keep its status, got %s and %s", loanApplication.BuyerID, loanApplication.Status)
+	}
+}
+
+func TestTransferApplicationBuyerRejectsDisbursed(t *testing.T) {
+	stub := newMockStub()
+	disbursed := testApplication("LA-1")
+	disbursed.Status = statusDisbursed
+	seedApplication(t, stub, disbursed)
+
+	if _, err := stub.invoke("TransferApplicationBuyer", "LA-1", "BUYER-2"); err == nil {
+		t.Fatal("expected a disbursed application not to change buyer")
+	}
+	if keys, _ := getKeysByPartialCompositeKey(stub, buyerIndexName, []string{"BUYER-2"}); len(keys) != 0 {
+		t.Fatal("expected no index entry for the rejected transfer")
+	}
+}
+
+func TestApproveEnforcesTermBounds(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"minTermMonths":120,"maxTermMonths":360}`)
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+
+	cases := []struct {
+		termMonths int
+		message    string
+	}{
+		{119, "below the minimum of 120"},
+		{120, ""},
+		{360, ""},
+		{361, "exceeds the maximum of 360"},
+	}
+	for _, c := range cases {
+		loanApplication := testApplication("LA-" + strconv.Itoa(c.termMonths))
+		loanApplication.TermMonths = c.termMonths
+		seedUnderReview(t, stub, loanApplication)
+
+		_, err := stub.as("rev1", roleReviewer).invoke("ApproveLoanApplication", loanApplication.ID, "", "", "n"+loanApplication.ID)
+		if c.message == "" && err != nil {
+			t.Errorf("expected a %d month term to be approved, got %v", c.termMonths, err)
+		}
+		if c.message != "" && (err == nil || !strings.Contains(err.Error(), c.message)) {
+			t.Errorf("expected a %d month term to be rejected with %q, got %v", c.termMonths, c.message, err)
+		}
+	}
+}
+
+func TestApproveEnforcesInterestRateBounds(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"minInterestRateBps":300,"maxInterestRateBps":800}`)
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":2.99,"B":3,"C":8,"D":8.01}`)
+
+	cases := []struct {
+		tier    string
+		message string
+	}{
+		{"A", "Interest rate 2.99% is below the minimum of 3.00%"},
+		{"B", ""},
+		{"C", ""},
+		{"D", "Interest rate 8.01% exceeds the maximum of 8.00%"},
+	}
+	for _, c := range cases {
+		loanApplication := testApplication("LA-" + c.tier)
+		loanApplication.CreditTier = c.tier
+		seedUnderReview(t, stub, loanApplication)
+
+		_, err := stub.as("rev1", roleReviewer).invoke("ApproveLoanApplication", loanApplication.ID, "", "", "n"+loanApplication.ID)
+		if c.message == "" && err != nil {
+			t.Errorf("expected tier %s to be approved, got %v", c.tier, err)
+		}
+		if c.message != "" && (err == nil || !strings.Contains(err.Error(), c.message)) {
+			t.Errorf("expected tier %s to be rejected with %q, got %v", c.tier, c.message, err)
+		}
+	}
+}
+
+func TestBatchApproveReportsEachApplication(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+	seedApplication(t, stub, testApplication("LA-2"))
+	locked := testApplication("LA-3")
+	locked.Locked = true
+	seedUnderReview(t, stub, locked)
+	seedUnderReview(t, stub, testApplication("LA-4"))
+
+	var results []batchResult
+	err := json.Unmarshal(stub.as("rev1", roleReviewer).mustInvoke(t, "BatchApprove", `["LA-1","LA-2","LA-3","LA-9","LA-4"]`, "batch1"), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"LA-1": true, "LA-2": false, "LA-3": false, "LA-9": false, "LA-4": true}
+	if len(results) != len(expected) {
+		t.Fatalf("expected a result per application, got %+v", results)
+	}
+	for _, result := range results {
+		if result.Success != expected[result.ID] || (!result.Success && result.Error == "") {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+	for id, success := range expected {
+		if id == "LA-9" {
+			continue
+		}
+		if approved := storedApplication(t, stub, id).Status == statusApproved; approved != success {
+			t.Errorf("expected %s approved to be %v", id, success)
+		}
+	}
+
+	// A failed approval leaves its nonce unused
+	stub.as("admin", roleAdmin).mustInvoke(t, "UnlockLoanApplication", "LA-3")
+	stub.as("rev1", roleReviewer).mustInvoke(t, "ApproveLoanApplication", "LA-3", "", "", "batch1")
+	if _, err := stub.as("customer", roleApplicant).invoke("BatchApprove", `["LA-2"]`, "batch2"); err == nil {
+		t.Fatal("expected batch approval to require the reviewer role")
+	}
+}
+
+// lastEventType Type of the most recent event
+func lastEventType(t *testing.T, stub *mockStub) string {
+	t.Helper()
+	var evt customEvent
+	err := json.Unmarshal(stub.lastEvent(t).payload, &evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return evt.Type
+}
+
+func TestConditionalApprovalBecomesFullApproval(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+	stub.as("rev1", roleReviewer)
+
+	stub.mustInvoke(t, "ApproveWithConditions", "LA-1", "250000", "", "n1", `["provide updated payslip","sign valuation"]`)
+	loanApplication := storedApplication(t, stub, "LA-1")
+	if loanApplication.Status != statusConditionallyApproved || len(loanApplication.Conditions) != 2 || loanApplication.ApprovedAmount != 250000 {
+		t.Fatalf("expected a conditional approval with two conditions, got %s with %+v", loanApplication.Status, loanApplication.Conditions)
+	}
+	if eventType := lastEventType(t, stub); eventType != "loanApplicationConditionalApproval" {
+		t.Fatalf("expected a conditional approval event, got %s", eventType)
+	}
+
+	stub.mustInvoke(t, "SatisfyCondition", "LA-1", "1")
+	loanApplication = storedApplication(t, stub, "LA-1")
+	if loanApplication.Status != statusConditionallyApproved || !loanApplication.Conditions[1].Satisfied || loanApplication.Conditions[1].SatisfiedBy != "rev1" {
+		t.Fatalf("expected one condition satisfied and the approval still conditional, got %s with %+v", loanApplication.Status, loanApplication.Conditions)
+	}
+	if _, err := stub.invoke("SatisfyCondition", "LA-1", "1"); err == nil {
+		t.Fatal("expected satisfying a condition twice to be rejected")
+	}
+	if _, err := stub.invoke("SatisfyCondition", "LA-1", "2"); err == nil {
+		t.Fatal("expected an out of range condition to be rejected")
+	}
+
+	stub.mustInvoke(t, "SatisfyCondition", "LA-1", "0")
+	if status := storedApplication(t, stub, "LA-1").Status; status != statusApproved {
+		t.Fatalf("expected approval once every condition is satisfied, got %s", status)
+	}
+	if eventType := lastEventType(t, stub); eventType != "loanApplicationApproval" {
+		t.Fatalf("expected an approval event, got %s", eventType)
+	}
+	if _, err := stub.invoke("SatisfyCondition", "LA-1", "0"); err == nil {
+		t.Fatal("expected an approved application to have no outstanding conditions")
+	}
+}
+
+func TestApproveWithConditionsRequiresConditions(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	seedUnderReview(t, stub, testApplication("LA-1"))
+	stub.as("rev1", roleReviewer)
+
+	for _, conditions := range []string{`[]`, `[" "]`, `not json`} {
+		if _, err := stub.invoke("ApproveWithConditions", "LA-1", "", "", "n1", conditions); err == nil {
+			t.Errorf("expected conditions %s to be rejected", conditions)
+		}
+	}
+	if status := storedApplication(t, stub, "LA-1").Status; status != statusUnderReview {
+		t.Fatalf("expected the application to stay under review, got %s", status)
+	}
+}
+
+func TestApproveBlocksAmountBelowMinimum(t *testing.T) {
+	stub := newMockStub()
+	stub.mustInvoke(t, "SetLoanParameters", `{"maxLoanToValue":0.8,"minApprovedAmount":50000}`)
+	stub.mustInvoke(t, "SetCreditTierRates", `{"A":5}`)
+	loanApplication := testApplication("LA-1")
+	loanApplication.RequestedAmount = 60000
+	loanApplication.FairMarketValue = 55000
+	seedUnderReview(t, stub, loanApplication)
+	stub.as("rev1", roleReviewer)
+
+	// Limiting the loan to 80% of the value leaves 44000, below the minimum
+	_, err := stub.invoke("ApproveLoanApplication", "LA-1", "44000", "", "n1")
+	if err == nil || !strings.Contains(err.Error(), "Approved amount 44000 for loan application LA-1 is below the minimum of 50000") {
+		t.Fatalf("expected the LTV-limited amount to be blocked, got %v", err)
+	}
+	if status := storedApplication(t, stub, "LA-1").Status; status != statusUnderReview {
+		t.Fatalf("expected the application to stay under review, got %s", status)
+	}
+
+	stub.mustInvoke(t, "ApproveLoanApplication", "LA-1", "50000", "", "n1")
+	if approved := storedApplication(t, stub, "LA-1").ApprovedAmount; approved != 50000 {
+		t.Fatalf("expected an amount at the minimum to be approved, got %d", approved)
+	}
+}